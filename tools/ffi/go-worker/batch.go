@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"haruki-assetstudio-go-ffi/batchrun"
+)
+
+// batchSummary is batchrun's summary plus the pool's counters.
+type batchSummary struct {
+	batchrun.Summary
+	Pool PoolStats `json:"pool"`
+}
+
+// runBatch processes bundles on jobs goroutines, each leasing a worker from
+// pool per bundle, and writes batchrun's JSON Lines report to out with the
+// pool stats in the summary line. It returns the number of failed bundles.
+func runBatch(ctx context.Context, pool *WorkerPool, bundles []string, jobs int, opts bundleOptions, out io.Writer) int {
+	summary := batchrun.Run(bundles, jobs, func(bundle string) (map[string]any, error) {
+		return processBundle(ctx, pool, bundle, opts)
+	}, out)
+	_ = batchrun.WriteSummary(out, batchSummary{Summary: summary, Pool: pool.Stats()})
+	return summary.Failed
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// defaultKillGrace is how long a worker gets to exit after SIGTERM before it
+// is killed.
+const defaultKillGrace = 2 * time.Second
+
+// CallContext states. The call and its context race to move a call out of
+// callRunning, so a response that is already in is returned even when the
+// context ends before the call gets to stop watching it.
+const (
+	callRunning int32 = iota
+	callAnswered
+	callAbandoned
+)
+
+// CallTimeoutError is returned by CallContext when its context ends before
+// the worker answers. The worker has been terminated by then. It unwraps to
+// the context's error, so errors.Is(err, context.DeadlineExceeded) works.
+type CallTimeoutError struct {
+	Operation string
+	Elapsed   time.Duration
+	// Killed reports that the worker ignored SIGTERM and needed SIGKILL.
+	Killed bool
+	Err    error
+}
+
+func (e *CallTimeoutError) Error() string {
+	how := "terminated"
+	if e.Killed {
+		how = "killed"
+	}
+	return fmt.Sprintf("worker %s call abandoned after %s, worker %s: %v", e.Operation, e.Elapsed.Round(time.Millisecond), how, e.Err)
+}
+
+func (e *CallTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// terminate asks the worker process to exit with SIGTERM and sends SIGKILL if
+// it is still running after grace, then waits for it. It reports whether
+// SIGKILL was needed. On Windows, where SIGTERM cannot be sent, the worker is
+// killed after grace.
+func (w *AssetStudioWorker) terminate(grace time.Duration) (killed bool) {
+	_ = w.cmd.Process.Signal(syscall.SIGTERM)
+	go w.wait()
+	select {
+	case <-w.exited:
+		return false
+	case <-time.After(grace):
+		_ = w.cmd.Process.Kill()
+		<-w.exited
+		return true
+	}
+}
@@ -1,3 +1,8 @@
 module haruki-assetstudio-worker-sample
 
-go 1.22
+go 1.25.1
+
+require haruki-assetstudio-go-ffi v0.0.0
+
+// The batch planning and reporting code is shared with the direct client.
+replace haruki-assetstudio-go-ffi => ../go
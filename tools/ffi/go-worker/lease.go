@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Lease is a worker checked out of a WorkerPool. The holder must end it with
+// Release or Discard; whichever comes first wins and later calls do nothing,
+// so `defer lease.Release()` after an earlier Discard is fine.
+type Lease struct {
+	pool    *WorkerPool
+	worker  *AssetStudioWorker
+	permits int
+	once    sync.Once
+	leak    *time.Timer
+}
+
+// Acquire waits for a free slot, or until ctx is done, and leases out an idle
+// worker or a newly started one.
+func (p *WorkerPool) Acquire(ctx context.Context) (*Lease, error) {
+	if err := p.acquireSlots(ctx, 1); err != nil {
+		return nil, err
+	}
+	worker, err := p.takeWorker()
+	if err != nil {
+		p.releaseSlots(1)
+		return nil, err
+	}
+	return p.newLease(worker, 1), nil
+}
+
+// AcquireExclusive waits until no other lease is held, like acquire_exclusive
+// in worker_pool.rs, and leases out a freshly started worker. Other Acquire
+// calls wait until the lease ends. Use it for work that must not share a
+// process, or the machine, with anything else.
+func (p *WorkerPool) AcquireExclusive(ctx context.Context) (*Lease, error) {
+	select {
+	case p.exclusive <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	err := p.acquireSlots(ctx, p.Size())
+	<-p.exclusive
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.lastActivity = time.Now()
+	p.mu.Unlock()
+	worker, err := p.spawn()
+	if err != nil {
+		p.releaseSlots(p.Size())
+		return nil, err
+	}
+	return p.newLease(worker, p.Size()), nil
+}
+
+// acquireSlots takes n slots, giving back the ones it got if ctx is done
+// first.
+func (p *WorkerPool) acquireSlots(ctx context.Context, n int) error {
+	for taken := range n {
+		if err := ctx.Err(); err != nil {
+			p.releaseSlots(taken)
+			return err
+		}
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			p.releaseSlots(taken)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (p *WorkerPool) releaseSlots(n int) {
+	for range n {
+		<-p.slots
+	}
+}
+
+func (p *WorkerPool) newLease(worker *AssetStudioWorker, permits int) *Lease {
+	lease := &Lease{pool: p, worker: worker, permits: permits}
+	if threshold := p.opts.LeakThreshold; threshold > 0 {
+		site := "unknown caller"
+		if _, file, line, ok := runtime.Caller(2); ok {
+			site = fmt.Sprintf("%s:%d", file, line)
+		}
+		acquired := time.Now()
+		lease.leak = time.AfterFunc(threshold, func() {
+			p.stats.leakedLeases.Add(1)
+			log.Printf("worker lease acquired at %s by %s still held after %s; missing Release or Discard?",
+				acquired.Format(time.RFC3339), site, threshold)
+		})
+	}
+	return lease
+}
+
+// Worker returns the leased worker. It must not be used after the lease ends.
+func (l *Lease) Worker() *AssetStudioWorker {
+	return l.worker
+}
+
+// Release gives the worker back to the pool, which replaces it if it broke
+// and recycles it if it reached PoolOptions.MaxCalls.
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		l.stopLeakTimer()
+		l.pool.putBack(l.worker)
+		l.pool.releaseSlots(l.permits)
+	})
+}
+
+// Discard kills the worker instead of returning it, for when the caller no
+// longer trusts its state. The next Acquire starts a new one.
+func (l *Lease) Discard() {
+	l.once.Do(func() {
+		l.stopLeakTimer()
+		l.pool.kill(l.worker)
+		l.pool.releaseSlots(l.permits)
+	})
+}
+
+func (l *Lease) stopLeakTimer() {
+	if l.leak != nil {
+		l.leak.Stop()
+	}
+}
@@ -3,16 +3,24 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"iter"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"haruki-assetstudio-go-ffi/batchrun"
 )
 
 type WorkerResponse struct {
@@ -29,46 +37,6 @@ type OperationResult struct {
 	Response  json.RawMessage `json:"response"`
 }
 
-type AssetInfo struct {
-	Index      int    `json:"index"`
-	TypeID     int    `json:"type_id"`
-	PathID     int64  `json:"path_id"`
-	Size       int64  `json:"size"`
-	Name       string `json:"name,omitempty"`
-	Container  string `json:"container,omitempty"`
-	Type       string `json:"type,omitempty"`
-	UniqueID   string `json:"unique_id,omitempty"`
-	SourceFile string `json:"source_file,omitempty"`
-}
-
-type OpenResponse struct {
-	Success   bool   `json:"success"`
-	ContextID int64  `json:"context_id"`
-	Error     string `json:"error,omitempty"`
-}
-
-type ListResponse struct {
-	Success    bool        `json:"success"`
-	Assets     []AssetInfo `json:"assets"`
-	NextOffset *int        `json:"next_offset"`
-	Error      string      `json:"error,omitempty"`
-}
-
-type ReadBatchResponse struct {
-	Success bool         `json:"success"`
-	Reads   []ReadResult `json:"reads"`
-	Error   string       `json:"error,omitempty"`
-}
-
-type ReadResult struct {
-	Success            bool       `json:"success"`
-	Asset              *AssetInfo `json:"asset"`
-	PayloadKind        string     `json:"payload_kind,omitempty"`
-	SuggestedExtension string     `json:"suggested_extension,omitempty"`
-	PayloadLen         int64      `json:"payload_len"`
-	Error              string     `json:"error,omitempty"`
-}
-
 type WorkerCallResult struct {
 	Response WorkerResponse
 	Payload  []byte
@@ -80,6 +48,17 @@ type AssetStudioWorker struct {
 	stdin  io.WriteCloser
 	stdout *bufio.Reader
 	lock   sync.Mutex
+	// broken is the protocol error that left the frame stream out of sync,
+	// or the *CallTimeoutError that ended the process; every later Call
+	// fails with it.
+	broken error
+	calls  int
+	stats  *poolCounters
+	// killGrace is how long terminate waits between SIGTERM and SIGKILL;
+	// zero means defaultKillGrace.
+	killGrace time.Duration
+	waitOnce  sync.Once
+	exited    chan struct{}
 }
 
 func NewAssetStudioWorker(workerPath, ffiLibrary string) (*AssetStudioWorker, error) {
@@ -110,38 +89,86 @@ func NewAssetStudioWorker(workerPath, ffiLibrary string) (*AssetStudioWorker, er
 		cmd:    cmd,
 		stdin:  stdin,
 		stdout: bufio.NewReader(stdoutPipe),
+		exited: make(chan struct{}),
 	}, nil
 }
 
-func (w *AssetStudioWorker) Call(operation string, request map[string]any) (*WorkerCallResult, error) {
+// Call sends one request and waits for its response and payload.
+func (w *AssetStudioWorker) Call(request Request) (*WorkerCallResult, error) {
+	return w.CallContext(context.Background(), request)
+}
+
+// CallContext is Call that gives up when ctx is done. The only way to abandon
+// a request the worker is still working on is to end the process, so the
+// worker is terminated (see terminate), marked broken and a
+// *CallTimeoutError returned; the pool replaces the worker when its lease is
+// released. A response that is already in when ctx ends is still returned.
+func (w *AssetStudioWorker) CallContext(ctx context.Context, request Request) (*WorkerCallResult, error) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
+	if w.broken != nil {
+		return nil, fmt.Errorf("worker is broken: %w", w.broken)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	var state atomic.Int32
+	killed := make(chan bool, 1)
+	stop := context.AfterFunc(ctx, func() {
+		if state.CompareAndSwap(callRunning, callAbandoned) {
+			killed <- w.terminate(cmp.Or(w.killGrace, defaultKillGrace))
+		}
+	})
+	result, err := w.roundTrip(request)
+	stop()
+	if !state.CompareAndSwap(callRunning, callAnswered) {
+		timeout := &CallTimeoutError{
+			Operation: request.Operation(),
+			Elapsed:   time.Since(started),
+			Killed:    <-killed,
+			Err:       ctx.Err(),
+		}
+		w.broken = timeout
+		w.stats.recordTimeout()
+		return nil, timeout
+	}
+	if err != nil {
+		if w.broken != nil {
+			w.stats.recordProtocolError()
+		}
+		return nil, err
+	}
+	w.calls++
+	w.stats.recordCall(time.Since(started))
+	return result, nil
+}
 
+// roundTrip writes one request frame and reads its response and payload.
+func (w *AssetStudioWorker) roundTrip(request Request) (*WorkerCallResult, error) {
 	id := w.nextID
 	w.nextID++
 	frame, err := json.Marshal(map[string]any{
-		"id": id,
-		"request": map[string]any{
-			"operation": operation,
-			"request":   request,
-		},
+		"id":      id,
+		"request": taggedRequest{Operation: request.Operation(), Request: request},
 	})
 	if err != nil {
 		return nil, err
 	}
 	if err := writeFrame(w.stdin, frame); err != nil {
-		return nil, err
+		return nil, w.fail(err)
 	}
 	responseFrame, err := readFrame(w.stdout)
 	if err != nil {
-		return nil, err
+		return nil, w.fail(err)
 	}
 	var response WorkerResponse
 	if err := json.Unmarshal(responseFrame, &response); err != nil {
-		return nil, err
+		return nil, w.fail(err)
 	}
 	if response.ID != id {
-		return nil, fmt.Errorf("worker response id mismatch: expected %d, got %d", id, response.ID)
+		return nil, w.fail(fmt.Errorf("worker response id mismatch: expected %d, got %d", id, response.ID))
 	}
 	if response.Error != "" {
 		return nil, errors.New(response.Error)
@@ -156,7 +183,7 @@ func (w *AssetStudioWorker) Call(operation string, request map[string]any) (*Wor
 	} else if response.PayloadLen > 0 {
 		payload, err = readFrame(w.stdout)
 		if err != nil {
-			return nil, err
+			return nil, w.fail(err)
 		}
 	}
 	if len(payload) != response.PayloadLen {
@@ -165,47 +192,69 @@ func (w *AssetStudioWorker) Call(operation string, request map[string]any) (*Wor
 	return &WorkerCallResult{Response: response, Payload: payload}, nil
 }
 
-func (w *AssetStudioWorker) Close() {
-	_ = w.stdin.Close()
-	_ = w.cmd.Wait()
+// fail marks the worker broken after a protocol error: a failed frame write
+// or read, an unparsable response or a response for another request. The
+// pool reaps broken workers instead of lending them out again.
+func (w *AssetStudioWorker) fail(err error) error {
+	w.broken = err
+	return err
 }
 
-type WorkerPool struct {
-	workers []*AssetStudioWorker
-	free    chan *AssetStudioWorker
+// Broken returns the protocol error or *CallTimeoutError that broke the
+// worker, or nil.
+func (w *AssetStudioWorker) Broken() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.broken
 }
 
-func NewWorkerPool(workerPath, ffiLibrary string, size int) (*WorkerPool, error) {
-	if size < 1 {
-		size = 1
-	}
-	pool := &WorkerPool{free: make(chan *AssetStudioWorker, size)}
-	for i := 0; i < size; i++ {
-		worker, err := NewAssetStudioWorker(workerPath, ffiLibrary)
-		if err != nil {
-			pool.Close()
-			return nil, err
-		}
-		pool.workers = append(pool.workers, worker)
-		pool.free <- worker
-	}
-	return pool, nil
+// Close closes the worker's stdin, which makes a healthy worker exit, and
+// waits for it.
+func (w *AssetStudioWorker) Close() {
+	_ = w.stdin.Close()
+	w.wait()
 }
 
-func (p *WorkerPool) Borrow() *AssetStudioWorker {
-	return <-p.free
+// wait waits for the process once; every path that ends a worker goes
+// through it, so cmd.Wait is never called twice.
+func (w *AssetStudioWorker) wait() {
+	w.waitOnce.Do(func() {
+		_ = w.cmd.Wait()
+		close(w.exited)
+	})
 }
 
-func (p *WorkerPool) Return(worker *AssetStudioWorker) {
-	p.free <- worker
+// reap kills the worker if it is still running, waits for it and returns its
+// exit code (-1 when it was killed by a signal).
+func (w *AssetStudioWorker) reap() int {
+	_ = w.cmd.Process.Kill()
+	_ = w.stdin.Close()
+	w.wait()
+	return w.cmd.ProcessState.ExitCode()
 }
 
-func (p *WorkerPool) Close() {
-	for _, worker := range p.workers {
-		worker.Close()
+// shutdown closes the worker's stdin and waits up to grace for it to exit
+// before killing it. It reports whether the worker had to be killed.
+func (w *AssetStudioWorker) shutdown(grace time.Duration) (killed bool) {
+	_ = w.stdin.Close()
+	go w.wait()
+	select {
+	case <-w.exited:
+		return false
+	case <-time.After(grace):
+		_ = w.cmd.Process.Kill()
+		<-w.exited
+		return true
 	}
 }
 
+// Calls returns how many calls the worker has completed.
+func (w *AssetStudioWorker) Calls() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.calls
+}
+
 func writeFrame(w io.Writer, payload []byte) error {
 	var header [8]byte
 	binary.LittleEndian.PutUint64(header[:], uint64(len(payload)))
@@ -246,83 +295,108 @@ func decodeBody[T any](result *WorkerCallResult, operation string) (T, error) {
 	return body, nil
 }
 
-func OpenContext(worker *AssetStudioWorker, bundle, unityVersion string) (int64, error) {
-	result, err := worker.Call("context_open", map[string]any{
-		"input_path":          bundle,
-		"asset_types":         []string{},
-		"unity_version":       unityVersion,
-		"filter_exclude_mode": false,
-		"filter_with_regex":   false,
-		"filter_by_name":      nil,
-		"filter_by_container": nil,
-		"filter_by_path_ids":  []int64{},
-		"load_all_assets":     true,
-		"include_assets":      false,
-	})
+func OpenContext(ctx context.Context, worker *AssetStudioWorker, bundle, unityVersion string) (int64, error) {
+	request := ContextOpenRequest{InputPath: bundle, LoadAllAssets: true}
+	if unityVersion != "" {
+		request.UnityVersion = &unityVersion
+	}
+	result, err := worker.CallContext(ctx, request)
 	if err != nil {
 		return 0, err
 	}
-	body, err := decodeBody[OpenResponse](result, "context_open")
+	body, err := decodeBody[OpenResponse](result, OperationContextOpen)
 	if err != nil {
 		return 0, err
 	}
 	if !body.Success {
-		return 0, fmt.Errorf("context_open failed: %s", body.Error)
+		return 0, fmt.Errorf("context_open failed: %s", deref(body.Error))
 	}
 	return body.ContextID, nil
 }
 
-func ListAllObjects(worker *AssetStudioWorker, contextID int64) ([]AssetInfo, error) {
+// defaultPageSize is the context_list_objects page size ListAllObjects uses.
+const defaultPageSize = 2048
+
+func ListAllObjects(ctx context.Context, worker *AssetStudioWorker, contextID int64) ([]AssetInfo, error) {
 	var assets []AssetInfo
-	offset := 0
-	for {
-		result, err := worker.Call("context_list_objects", map[string]any{
-			"context_id": contextID,
-			"offset":     offset,
-			"limit":      2048,
-		})
-		if err != nil {
-			return nil, err
-		}
-		body, err := decodeBody[ListResponse](result, "context_list_objects")
+	for asset, err := range Objects(ctx, worker, contextID, defaultPageSize) {
 		if err != nil {
 			return nil, err
 		}
-		if !body.Success {
-			return nil, fmt.Errorf("context_list_objects failed: %s", body.Error)
-		}
-		assets = append(assets, body.Assets...)
-		if body.NextOffset == nil {
-			return assets, nil
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+// Objects iterates over a context's object table, requesting pageSize entries
+// per context_list_objects call as the loop needs them; zero or less means
+// defaultPageSize. Keep pageSize within the library's
+// max_object_table_page_limit, which the worker protocol does not report.
+// Breaking out of the loop sends no further
+// requests. A failed call is yielded once with a zero AssetInfo and ends the
+// iteration.
+func Objects(ctx context.Context, worker *AssetStudioWorker, contextID int64, pageSize int) iter.Seq2[AssetInfo, error] {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	return func(yield func(AssetInfo, error) bool) {
+		offset := 0
+		for {
+			result, err := worker.CallContext(ctx, ContextListObjectsRequest{
+				ContextID: contextID,
+				Offset:    offset,
+				Limit:     pageSize,
+			})
+			if err != nil {
+				yield(AssetInfo{}, err)
+				return
+			}
+			body, err := decodeBody[ListResponse](result, OperationContextListObjects)
+			if err != nil {
+				yield(AssetInfo{}, err)
+				return
+			}
+			if !body.Success {
+				yield(AssetInfo{}, fmt.Errorf("context_list_objects failed: %s", deref(body.Error)))
+				return
+			}
+			for _, asset := range body.Assets {
+				if !yield(asset, nil) {
+					return
+				}
+			}
+			if body.NextOffset == nil {
+				return
+			}
+			offset = *body.NextOffset
 		}
-		offset = *body.NextOffset
 	}
 }
 
-func ReadTexture2D(worker *AssetStudioWorker, contextID int64, assets []AssetInfo) (map[string]any, error) {
-	var objects []map[string]any
+func ReadTexture2D(ctx context.Context, worker *AssetStudioWorker, contextID int64, assets []AssetInfo) (map[string]any, error) {
+	var objects []ReadObjectItem
 	for _, asset := range assets {
-		if asset.Type == "Texture2D" {
-			objects = append(objects, map[string]any{
-				"path_id":      asset.PathID,
-				"kind":         "image",
-				"image_format": "raw_rgba",
+		if deref(asset.Type) == "Texture2D" {
+			objects = append(objects, ReadObjectItem{
+				PathID:      asset.PathID,
+				Kind:        "image",
+				ImageFormat: "raw_rgba",
 			})
 		}
 	}
-	result, err := worker.Call("context_read_objects", map[string]any{
-		"context_id": contextID,
-		"objects":    objects,
+	result, err := worker.CallContext(ctx, ContextReadObjectsRequest{
+		ContextID: contextID,
+		Objects:   objects,
 	})
 	if err != nil {
 		return nil, err
 	}
-	body, err := decodeBody[ReadBatchResponse](result, "context_read_objects")
+	body, err := decodeBody[ReadBatchResponse](result, OperationContextReadObjects)
 	if err != nil {
 		return nil, err
 	}
 	if !body.Success {
-		return nil, fmt.Errorf("context_read_objects failed: %s", body.Error)
+		return nil, fmt.Errorf("context_read_objects failed: %s", deref(body.Error))
 	}
 	reads := make([]map[string]any, 0, len(body.Reads))
 	for _, read := range body.Reads {
@@ -346,16 +420,16 @@ func ReadTexture2D(worker *AssetStudioWorker, contextID int64, assets []AssetInf
 }
 
 func CloseContext(worker *AssetStudioWorker, contextID int64) error {
-	result, err := worker.Call("context_close", map[string]any{"context_id": contextID})
+	result, err := worker.Call(ContextCloseRequest{ContextID: contextID})
 	if err != nil {
 		return err
 	}
-	body, err := decodeBody[map[string]any](result, "context_close")
+	body, err := decodeBody[CloseResponse](result, OperationContextClose)
 	if err != nil {
 		return err
 	}
-	if success, _ := body["success"].(bool); !success {
-		return fmt.Errorf("context_close failed: %v", body["error"])
+	if !body.Success {
+		return fmt.Errorf("context_close failed: %s", deref(body.Error))
 	}
 	return nil
 }
@@ -364,56 +438,122 @@ func main() {
 	ffiLibrary := flag.String("ffi-library", "", "Path to HarukiAssetStudioFFI dynamic library")
 	workerPath := flag.String("ffi-worker", "target/release/assetstudio_ffi_worker", "Path to assetstudio_ffi_worker")
 	bundle := flag.String("bundle", "", "UnityFS bundle path")
+	bundleDir := flag.String("bundle-dir", "", "Process every file under this directory, e.g. an asset_save_dir")
+	glob := flag.String("glob", "", "Process the bundles matching this pattern; with --bundle-dir, filter the walked files by it")
 	unityVersion := flag.String("unity-version", "2022.3.21f1", "Unity version fallback")
-	poolSize := flag.Int("pool-size", 2, "Number of worker processes")
+	poolSize := flag.Int("pool-size", 2, "Number of worker processes, and bundles processed at once with --bundle-dir or --glob")
 	readImages := flag.Bool("read-images", false, "Read Texture2D raw_rgba payloads")
+	maxCalls := flag.Int("max-calls", envInt("HARUKI_ASSET_STUDIO_FFI_WORKER_MAX_CALLS", 256), "Recycle a worker after this many calls (0 never recycles)")
+	idleTimeout := flag.Duration("idle-timeout", time.Duration(envInt("HARUKI_ASSET_STUDIO_FFI_WORKER_IDLE_TIMEOUT_SECONDS", 60))*time.Second, "Shut idle workers down after this long without calls (0 keeps them)")
+	leakThreshold := flag.Duration("lease-leak-threshold", 10*time.Minute, "Log worker leases held longer than this (0 disables)")
+	timeout := flag.Duration("timeout", 0, "Give up on a bundle after this long, e.g. 2m, terminating its worker (0 means no limit)")
 	flag.Parse()
-	if *ffiLibrary == "" || *bundle == "" {
-		panic("--ffi-library and --bundle are required")
-	}
-	bundlePath, err := filepath.Abs(*bundle)
+	batch := *bundleDir != "" || *glob != ""
+	if *ffiLibrary == "" || (*bundle == "") != batch {
+		panic("--ffi-library and one of --bundle or --bundle-dir/--glob are required")
+	}
+	pool, err := NewWorkerPoolWithOptions(*workerPath, *ffiLibrary, *poolSize, PoolOptions{
+		MaxCalls:      *maxCalls,
+		IdleTimeout:   *idleTimeout,
+		LeakThreshold: *leakThreshold,
+	})
 	if err != nil {
 		panic(err)
 	}
-	pool, err := NewWorkerPool(*workerPath, *ffiLibrary, *poolSize)
+	defer pool.Close()
+	ctx := context.Background()
+	opts := bundleOptions{unityVersion: *unityVersion, readImages: *readImages, timeout: *timeout}
+
+	if batch {
+		bundles, err := batchrun.Find(*bundleDir, *glob)
+		if err != nil {
+			panic(err)
+		}
+		if failed := runBatch(ctx, pool, bundles, pool.Size(), opts, os.Stdout); failed > 0 {
+			pool.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	output, err := processBundle(ctx, pool, *bundle, opts)
 	if err != nil {
 		panic(err)
 	}
-	defer pool.Close()
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(output)
+}
 
-	worker := pool.Borrow()
-	defer pool.Return(worker)
+// envInt reads a non-negative integer setting from the environment, the way
+// the Rust service reads its HARUKI_ASSET_STUDIO_FFI_WORKER_* overrides.
+func envInt(name string, fallback int) int {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		panic(fmt.Sprintf("%s must be a non-negative integer, got %q", name, value))
+	}
+	return n
+}
+
+// bundleOptions are the per-bundle settings from the command line.
+type bundleOptions struct {
+	unityVersion string
+	readImages   bool
+	timeout      time.Duration
+}
 
-	contextID, err := OpenContext(worker, bundlePath, *unityVersion)
+// processBundle leases a worker, opens bundle on it, lists its objects and
+// optionally reads its textures, returning the JSON summary for the bundle.
+// With opts.timeout, a call still running at the deadline terminates the
+// worker and fails the bundle with a *CallTimeoutError.
+func processBundle(ctx context.Context, pool *WorkerPool, bundle string, opts bundleOptions) (output map[string]any, err error) {
+	bundlePath, err := filepath.Abs(bundle)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	lease, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release()
+	worker := lease.Worker()
+
+	contextID, err := OpenContext(ctx, worker, bundlePath, opts.unityVersion)
+	if err != nil {
+		return nil, err
 	}
 	defer func() {
-		if err := CloseContext(worker, contextID); err != nil {
-			panic(err)
+		if closeErr := CloseContext(worker, contextID); closeErr != nil && err == nil {
+			output, err = nil, closeErr
 		}
 	}()
 
-	assets, err := ListAllObjects(worker, contextID)
+	assets, err := ListAllObjects(ctx, worker, contextID)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	types := map[string]int{}
 	for _, asset := range assets {
-		types[asset.Type]++
+		types[deref(asset.Type)]++
 	}
-	output := map[string]any{
+	output = map[string]any{
 		"asset_count": len(assets),
 		"types":       types,
 	}
-	if *readImages {
-		imageReads, err := ReadTexture2D(worker, contextID, assets)
+	if opts.readImages {
+		imageReads, err := ReadTexture2D(ctx, worker, contextID, assets)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		output["image_reads"] = imageReads
 	}
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	_ = encoder.Encode(output)
+	return output, nil
 }
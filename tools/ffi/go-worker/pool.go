@@ -0,0 +1,349 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats is a snapshot of a WorkerPool's health counters, named after the
+// WorkerPoolStats of the Rust worker pool.
+type PoolStats struct {
+	Spawned        int64 `json:"spawned"`
+	SpawnFailures  int64 `json:"spawn_failures"`
+	ProtocolErrors int64 `json:"protocol_errors"`
+	CallTimeouts   int64 `json:"call_timeouts"`
+	Killed         int64 `json:"killed"`
+	Recycled       int64 `json:"recycled"`
+	CompletedCalls int64 `json:"completed_calls"`
+	MaxCallMS      int64 `json:"max_call_ms"`
+	// IdleReaped counts workers shut down by the idle reaper, and
+	// IdleReapDeferred the reaps it put off because a worker was leased.
+	IdleReaped        int64 `json:"idle_reaped"`
+	IdleReapDeferred  int64 `json:"idle_reap_deferred"`
+	GracefulShutdowns int64 `json:"graceful_shutdowns"`
+	ForcedShutdowns   int64 `json:"forced_shutdowns"`
+	LeakedLeases      int64 `json:"leaked_leases"`
+	LastExitCode      *int  `json:"last_exit_code"`
+	Idle              int   `json:"idle"`
+	Leased            int   `json:"leased"`
+}
+
+// PoolOptions mirror the Rust pool's worker_max_calls and
+// worker_idle_timeout_seconds settings.
+type PoolOptions struct {
+	// MaxCalls recycles a worker once it has completed this many calls; 0
+	// never recycles.
+	MaxCalls int
+	// IdleTimeout shuts every idle worker down once the pool has seen no
+	// Acquire or Release for this long; 0 keeps them forever. Acquire starts
+	// new workers as needed afterwards.
+	IdleTimeout time.Duration
+	// LeakThreshold logs a lease that is still held after this long, which
+	// usually means a missing Release or Discard; 0 disables the check.
+	LeakThreshold time.Duration
+	// KillGrace is how long a worker whose CallContext was cancelled gets to
+	// exit after SIGTERM before it is killed; 0 means 2s.
+	KillGrace time.Duration
+}
+
+// shutdownGrace is how long a worker gets to exit after its stdin is closed
+// before it is killed, like WORKER_SHUTDOWN_TIMEOUT in worker_pool.rs.
+const shutdownGrace = 5 * time.Second
+
+// poolCounters is shared by a pool and its workers. A nil *poolCounters
+// (a worker outside any pool) records nothing.
+type poolCounters struct {
+	spawned        atomic.Int64
+	spawnFailures  atomic.Int64
+	protocolErrors atomic.Int64
+	callTimeouts   atomic.Int64
+	killed         atomic.Int64
+	recycled       atomic.Int64
+	completedCalls atomic.Int64
+	maxCallMS      atomic.Int64
+
+	idleReaped        atomic.Int64
+	idleReapDeferred  atomic.Int64
+	gracefulShutdowns atomic.Int64
+	forcedShutdowns   atomic.Int64
+	leakedLeases      atomic.Int64
+}
+
+func (c *poolCounters) recordCall(d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.completedCalls.Add(1)
+	ms := d.Milliseconds()
+	for {
+		current := c.maxCallMS.Load()
+		if ms <= current || c.maxCallMS.CompareAndSwap(current, ms) {
+			return
+		}
+	}
+}
+
+func (c *poolCounters) recordProtocolError() {
+	if c != nil {
+		c.protocolErrors.Add(1)
+	}
+}
+
+func (c *poolCounters) recordTimeout() {
+	if c != nil {
+		c.callTimeouts.Add(1)
+	}
+}
+
+// WorkerPool leases out at most size workers at a time. A worker returned
+// broken (see AssetStudioWorker.Broken) is reaped and replaced, so one crashed
+// or desynchronised worker process does not fail every later bundle.
+type WorkerPool struct {
+	workerPath string
+	ffiLibrary string
+	opts       PoolOptions
+	// slots holds one token per leased worker; an exclusive lease holds them all.
+	slots chan struct{}
+	// exclusive serialises AcquireExclusive so two of them cannot each hold
+	// part of the slots.
+	exclusive chan struct{}
+	stats     poolCounters
+	done      chan struct{}
+
+	mu           sync.Mutex
+	idle         []*AssetStudioWorker
+	closed       bool
+	lastExitCode *int
+	lastActivity time.Time
+}
+
+// NewWorkerPool starts size workers up front so a bad --worker or
+// --ffi-library fails before any bundle is processed. They are never recycled
+// or reaped; see NewWorkerPoolWithOptions.
+func NewWorkerPool(workerPath, ffiLibrary string, size int) (*WorkerPool, error) {
+	return NewWorkerPoolWithOptions(workerPath, ffiLibrary, size, PoolOptions{})
+}
+
+// NewWorkerPoolWithOptions is NewWorkerPool with max-calls recycling and idle
+// reaping.
+func NewWorkerPoolWithOptions(workerPath, ffiLibrary string, size int, opts PoolOptions) (*WorkerPool, error) {
+	size = max(size, 1)
+	pool := &WorkerPool{
+		workerPath:   workerPath,
+		ffiLibrary:   ffiLibrary,
+		opts:         opts,
+		slots:        make(chan struct{}, size),
+		exclusive:    make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		lastActivity: time.Now(),
+	}
+	for range size {
+		worker, err := pool.spawn()
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		pool.idle = append(pool.idle, worker)
+	}
+	if opts.IdleTimeout > 0 {
+		go pool.reapIdle()
+	}
+	return pool, nil
+}
+
+// Size is the most workers the pool leases out at once.
+func (p *WorkerPool) Size() int {
+	return cap(p.slots)
+}
+
+func (p *WorkerPool) spawn() (*AssetStudioWorker, error) {
+	worker, err := NewAssetStudioWorker(p.workerPath, p.ffiLibrary)
+	if err != nil {
+		p.stats.spawnFailures.Add(1)
+		return nil, err
+	}
+	worker.stats = &p.stats
+	worker.killGrace = p.opts.KillGrace
+	p.stats.spawned.Add(1)
+	return worker, nil
+}
+
+// takeWorker pops an idle worker, starting a new one when none is idle
+// (after recycling, idle reaping or a failed respawn). The caller holds a
+// slot.
+func (p *WorkerPool) takeWorker() (*AssetStudioWorker, error) {
+	p.mu.Lock()
+	p.lastActivity = time.Now()
+	if n := len(p.idle); n > 0 {
+		worker := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return worker, nil
+	}
+	p.mu.Unlock()
+	return p.spawn()
+}
+
+// putBack takes a leased worker back. A broken worker is reaped, its exit
+// code recorded, and a replacement started in its place. A worker that has
+// reached PoolOptions.MaxCalls is shut down; the next Acquire starts a fresh
+// one, as does one for a worker that would grow the pool beyond its size.
+func (p *WorkerPool) putBack(worker *AssetStudioWorker) {
+	if cause := worker.Broken(); cause != nil {
+		p.replace(worker, cause)
+		return
+	}
+	if p.opts.MaxCalls > 0 && worker.Calls() >= p.opts.MaxCalls {
+		p.stats.recycled.Add(1)
+		p.shutdown(worker)
+		return
+	}
+	p.mu.Lock()
+	p.lastActivity = time.Now()
+	if !p.closed && len(p.idle) < p.Size() {
+		p.idle = append(p.idle, worker)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.shutdown(worker)
+}
+
+// kill stops a worker at once, for Lease.Discard.
+func (p *WorkerPool) kill(worker *AssetStudioWorker) {
+	code := worker.reap()
+	p.stats.killed.Add(1)
+	p.stats.forcedShutdowns.Add(1)
+	p.mu.Lock()
+	p.lastExitCode = &code
+	p.lastActivity = time.Now()
+	p.mu.Unlock()
+}
+
+// shutdown stops a healthy worker, killing it if it ignores the closed stdin
+// for shutdownGrace.
+func (p *WorkerPool) shutdown(worker *AssetStudioWorker) {
+	if worker.shutdown(shutdownGrace) {
+		p.stats.forcedShutdowns.Add(1)
+		p.stats.killed.Add(1)
+	} else {
+		p.stats.gracefulShutdowns.Add(1)
+	}
+}
+
+// reapIdle shuts the idle workers down whenever the pool has been inactive
+// for opts.IdleTimeout, until Close.
+func (p *WorkerPool) reapIdle() {
+	timer := time.NewTimer(p.opts.IdleTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-timer.C:
+		}
+		timer.Reset(p.reapIfIdle())
+	}
+}
+
+// reapIfIdle takes the idle workers if the pool has been inactive for
+// opts.IdleTimeout and nothing is leased, and returns how long to wait
+// before checking again. It only holds p.mu briefly, so it never blocks
+// Acquire; while a worker is leased the reap is deferred instead.
+func (p *WorkerPool) reapIfIdle() time.Duration {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return p.opts.IdleTimeout
+	}
+	if wait := p.opts.IdleTimeout - time.Since(p.lastActivity); wait > 0 {
+		p.mu.Unlock()
+		return wait
+	}
+	if len(p.slots) > 0 {
+		p.mu.Unlock()
+		p.stats.idleReapDeferred.Add(1)
+		return min(p.opts.IdleTimeout, time.Second)
+	}
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	if len(idle) > 0 {
+		p.stats.idleReaped.Add(int64(len(idle)))
+		log.Printf("shutting down %d idle workers after %s", len(idle), p.opts.IdleTimeout)
+	}
+	for _, worker := range idle {
+		p.shutdown(worker)
+	}
+	return p.opts.IdleTimeout
+}
+
+func (p *WorkerPool) replace(worker *AssetStudioWorker, cause error) {
+	code := worker.reap()
+	p.stats.killed.Add(1)
+	p.mu.Lock()
+	p.lastExitCode = &code
+	closed := p.closed
+	p.mu.Unlock()
+	log.Printf("broken worker pid %d reaped (exit code %d): %v", worker.cmd.Process.Pid, code, cause)
+	if closed {
+		return
+	}
+	replacement, err := p.spawn()
+	if err != nil {
+		log.Printf("worker replacement failed, will retry on next acquire: %v", err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		replacement.Close()
+		return
+	}
+	p.idle = append(p.idle, replacement)
+}
+
+// Stats returns a snapshot of the pool's health counters.
+func (p *WorkerPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		Spawned:        p.stats.spawned.Load(),
+		SpawnFailures:  p.stats.spawnFailures.Load(),
+		ProtocolErrors: p.stats.protocolErrors.Load(),
+		CallTimeouts:   p.stats.callTimeouts.Load(),
+		Killed:         p.stats.killed.Load(),
+		Recycled:       p.stats.recycled.Load(),
+		CompletedCalls: p.stats.completedCalls.Load(),
+		MaxCallMS:      p.stats.maxCallMS.Load(),
+
+		IdleReaped:        p.stats.idleReaped.Load(),
+		IdleReapDeferred:  p.stats.idleReapDeferred.Load(),
+		GracefulShutdowns: p.stats.gracefulShutdowns.Load(),
+		ForcedShutdowns:   p.stats.forcedShutdowns.Load(),
+		LeakedLeases:      p.stats.leakedLeases.Load(),
+		LastExitCode:      p.lastExitCode,
+		Idle:              len(p.idle),
+		Leased:            len(p.slots),
+	}
+}
+
+// Close shuts down the idle workers; leased workers are shut down when their
+// lease is released.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+	for _, worker := range idle {
+		p.shutdown(worker)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestMain doubles as a fake assetstudio_ffi_worker when the pool tests start
+// the test binary itself as the worker.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("HARUKI_FAKE_WORKER_MODE"); mode != "" {
+		os.Exit(fakeWorker(mode))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeWorker answers every request with an empty success. In "crash" mode it
+// exits with status 3 on the second request; in "bad-id" mode it answers the
+// second request with the wrong id. In "hang" mode it never answers the
+// second request, and "stubborn" does the same while ignoring SIGTERM.
+func fakeWorker(mode string) int {
+	if mode == "stubborn" {
+		signal.Ignore(syscall.SIGTERM)
+	}
+	in := bufio.NewReader(os.Stdin)
+	for served := 0; ; served++ {
+		frame, err := readFrame(in)
+		if err != nil {
+			return 0
+		}
+		var request struct {
+			ID uint64 `json:"id"`
+		}
+		if err := json.Unmarshal(frame, &request); err != nil {
+			return 2
+		}
+		if served == 1 {
+			switch mode {
+			case "crash":
+				return 3
+			case "bad-id":
+				request.ID += 100
+			case "hang", "stubborn":
+				time.Sleep(time.Hour)
+			}
+		}
+		response, _ := json.Marshal(map[string]any{
+			"id":       request.ID,
+			"status":   0,
+			"response": map[string]any{"operation": OperationContextClose, "response": map[string]any{}},
+		})
+		if err := writeFrame(os.Stdout, response); err != nil {
+			return 2
+		}
+	}
+}
+
+func newFakePool(t *testing.T, mode string, size int, opts PoolOptions) *WorkerPool {
+	t.Helper()
+	t.Setenv("HARUKI_FAKE_WORKER_MODE", mode)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewWorkerPoolWithOptions(exe, exe, size, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func borrowAndCall(t *testing.T, pool *WorkerPool, calls int) error {
+	t.Helper()
+	lease, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+	worker := lease.Worker()
+	for range calls {
+		if _, err := worker.Call(ContextCloseRequest{ContextID: 1}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPoolRespawnsBrokenWorkers(t *testing.T) {
+	for mode, wantExit := range map[string]int{"crash": 3, "bad-id": -1} {
+		t.Run(mode, func(t *testing.T) {
+			pool := newFakePool(t, mode, 1, PoolOptions{})
+			if err := borrowAndCall(t, pool, 2); err == nil {
+				t.Fatal("second call on a failing worker succeeded")
+			}
+			stats := pool.Stats()
+			if stats.Spawned != 2 || stats.Killed != 1 || stats.ProtocolErrors != 1 || stats.Idle != 1 {
+				t.Errorf("stats after failure = %+v", stats)
+			}
+			if stats.LastExitCode == nil || *stats.LastExitCode != wantExit {
+				t.Errorf("last exit code = %v, want %d", stats.LastExitCode, wantExit)
+			}
+			if err := borrowAndCall(t, pool, 1); err != nil {
+				t.Fatalf("replacement worker: %v", err)
+			}
+			if stats := pool.Stats(); stats.CompletedCalls != 2 || stats.Leased != 0 {
+				t.Errorf("stats after replacement = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestBrokenWorkerRejectsCalls(t *testing.T) {
+	pool := newFakePool(t, "crash", 1, PoolOptions{})
+	lease, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+	worker := lease.Worker()
+	if _, err := worker.Call(ContextCloseRequest{ContextID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worker.Call(ContextCloseRequest{ContextID: 1}); err == nil || worker.Broken() == nil {
+		t.Fatalf("crash not detected: err=%v broken=%v", err, worker.Broken())
+	}
+	before := pool.Stats().ProtocolErrors
+	if _, err := worker.Call(ContextCloseRequest{ContextID: 1}); err == nil {
+		t.Fatal("call on a broken worker succeeded")
+	}
+	if after := pool.Stats().ProtocolErrors; after != before {
+		t.Errorf("protocol errors went from %d to %d on a rejected call", before, after)
+	}
+}
+
+func TestPoolRecyclesAfterMaxCalls(t *testing.T) {
+	pool := newFakePool(t, "ok", 1, PoolOptions{MaxCalls: 2})
+	if err := borrowAndCall(t, pool, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Recycled != 0 || stats.Idle != 1 {
+		t.Fatalf("recycled below max calls: %+v", stats)
+	}
+	if err := borrowAndCall(t, pool, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Recycled != 1 || stats.GracefulShutdowns != 1 || stats.Idle != 0 {
+		t.Fatalf("stats after max calls = %+v", stats)
+	}
+	if err := borrowAndCall(t, pool, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Spawned != 2 || stats.Idle != 1 {
+		t.Errorf("stats after regrowing = %+v", stats)
+	}
+}
+
+func waitForStats(t *testing.T, pool *WorkerPool, ok func(PoolStats) bool) PoolStats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := pool.Stats()
+		if ok(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for pool stats, last %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolReapsIdleWorkers(t *testing.T) {
+	pool := newFakePool(t, "ok", 2, PoolOptions{IdleTimeout: 50 * time.Millisecond})
+	stats := waitForStats(t, pool, func(s PoolStats) bool { return s.GracefulShutdowns == 2 })
+	if stats.Idle != 0 || stats.IdleReaped != 2 {
+		t.Errorf("stats after idle reap = %+v", stats)
+	}
+	if err := borrowAndCall(t, pool, 1); err != nil {
+		t.Fatalf("borrow after idle reap: %v", err)
+	}
+	if stats := pool.Stats(); stats.Spawned != 3 || stats.Idle != 1 {
+		t.Errorf("stats after regrowing = %+v", stats)
+	}
+}
+
+func TestIdleReaperDefersWhileLeasedAndNeverBlocksAcquire(t *testing.T) {
+	pool := newFakePool(t, "ok", 2, PoolOptions{IdleTimeout: 50 * time.Millisecond})
+	held, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForStats(t, pool, func(s PoolStats) bool { return s.IdleReapDeferred > 0 })
+	borrowed := make(chan error, 1)
+	go func() {
+		lease, err := pool.Acquire(context.Background())
+		if err == nil {
+			lease.Release()
+		}
+		borrowed <- err
+	}()
+	select {
+	case err := <-borrowed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire blocked behind the idle reaper")
+	}
+	if stats := pool.Stats(); stats.IdleReaped != 0 {
+		t.Errorf("reaped while a worker was leased: %+v", stats)
+	}
+	held.Release()
+	waitForStats(t, pool, func(s PoolStats) bool { return s.GracefulShutdowns == 2 && s.Idle == 0 })
+}
+
+func TestAcquireHonoursDeadline(t *testing.T) {
+	pool := newFakePool(t, "ok", 1, PoolOptions{})
+	held, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire on a full pool = %v, want deadline exceeded", err)
+	}
+	held.Release()
+	held.Release()
+	if stats := pool.Stats(); stats.Leased != 0 || stats.Idle != 1 {
+		t.Fatalf("stats after double release = %+v", stats)
+	}
+	if err := borrowAndCall(t, pool, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaseDiscardKillsWorker(t *testing.T) {
+	pool := newFakePool(t, "ok", 1, PoolOptions{})
+	lease, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease.Discard()
+	lease.Release()
+	if stats := pool.Stats(); stats.Killed != 1 || stats.Idle != 0 || stats.Leased != 0 {
+		t.Fatalf("stats after discard = %+v", stats)
+	}
+	if err := borrowAndCall(t, pool, 1); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Spawned != 2 {
+		t.Errorf("stats after discard and acquire = %+v", stats)
+	}
+}
+
+func TestAcquireExclusiveWaitsForAllLeases(t *testing.T) {
+	pool := newFakePool(t, "ok", 2, PoolOptions{})
+	held, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.AcquireExclusive(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AcquireExclusive with a lease held = %v, want deadline exceeded", err)
+	}
+	if stats := pool.Stats(); stats.Leased != 1 {
+		t.Fatalf("timed-out exclusive acquire kept slots: %+v", stats)
+	}
+	held.Release()
+
+	exclusive, err := pool.AcquireExclusive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exclusive.Worker().Call(ContextCloseRequest{ContextID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if stats := pool.Stats(); stats.Leased != 2 || stats.Spawned != 3 {
+		t.Fatalf("stats with exclusive lease = %+v", stats)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire during exclusive lease = %v, want deadline exceeded", err)
+	}
+	exclusive.Release()
+	if stats := pool.Stats(); stats.Leased != 0 || stats.Idle != 2 {
+		t.Errorf("stats after exclusive release = %+v", stats)
+	}
+}
+
+func TestLeakedLeaseIsLogged(t *testing.T) {
+	pool := newFakePool(t, "ok", 1, PoolOptions{LeakThreshold: 20 * time.Millisecond})
+	lease, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForStats(t, pool, func(s PoolStats) bool { return s.LeakedLeases == 1 })
+	lease.Release()
+
+	lease, err = pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease.Release()
+	time.Sleep(40 * time.Millisecond)
+	if stats := pool.Stats(); stats.LeakedLeases != 1 {
+		t.Errorf("released lease reported as leaked: %+v", stats)
+	}
+}
+
+func TestCallContextTerminatesHungWorker(t *testing.T) {
+	for mode, wantKilled := range map[string]bool{"hang": false, "stubborn": true} {
+		t.Run(mode, func(t *testing.T) {
+			if mode == "stubborn" && runtime.GOOS == "windows" {
+				t.Skip("SIGTERM cannot be ignored on Windows")
+			}
+			pool := newFakePool(t, mode, 1, PoolOptions{KillGrace: 100 * time.Millisecond})
+			lease, err := pool.Acquire(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			worker := lease.Worker()
+			if _, err := worker.CallContext(context.Background(), ContextCloseRequest{ContextID: 1}); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err = worker.CallContext(ctx, ContextCloseRequest{ContextID: 1})
+			var timeout *CallTimeoutError
+			if !errors.As(err, &timeout) || !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("CallContext on a hung worker = %v, want *CallTimeoutError", err)
+			}
+			if timeout.Killed != wantKilled || timeout.Operation != OperationContextClose {
+				t.Errorf("timeout error = %+v, want Killed=%v", timeout, wantKilled)
+			}
+			if !errors.Is(worker.Broken(), context.DeadlineExceeded) {
+				t.Errorf("worker not marked broken by the timeout: %v", worker.Broken())
+			}
+			lease.Release()
+
+			stats := pool.Stats()
+			if stats.CallTimeouts != 1 || stats.ProtocolErrors != 0 || stats.Killed != 1 || stats.Idle != 1 {
+				t.Errorf("stats after timeout = %+v", stats)
+			}
+			if err := borrowAndCall(t, pool, 1); err != nil {
+				t.Fatalf("replacement worker: %v", err)
+			}
+		})
+	}
+}
+
+func TestCallContextWithDoneContextKeepsWorker(t *testing.T) {
+	pool := newFakePool(t, "ok", 1, PoolOptions{})
+	lease, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lease.Worker().CallContext(ctx, ContextCloseRequest{ContextID: 1}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CallContext with a cancelled context = %v", err)
+	}
+	if err := lease.Worker().Broken(); err != nil {
+		t.Fatalf("worker broken by a call that never started: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import "encoding/json"
+
+// Operation names: the snake_case serde tags of AssetStudioFfiRequest and
+// AssetStudioFfiResponse in crates/assetstudio-ffi/src/types.rs.
+const (
+	OperationContextOpen        = "context_open"
+	OperationContextListObjects = "context_list_objects"
+	OperationContextClose       = "context_close"
+	OperationContextReadObjects = "context_read_objects"
+)
+
+// Request is one variant of AssetStudioFfiRequest. The types below mirror
+// the serde shapes in types.rs field for field: Option fields are pointers and
+// encode as null when unset, like serde does. protocol_test.go checks them
+// against types.rs and the golden frames in testdata/protocol.
+type Request interface {
+	Operation() string
+}
+
+// taggedRequest is the adjacently tagged encoding of AssetStudioFfiRequest.
+type taggedRequest struct {
+	Operation string  `json:"operation"`
+	Request   Request `json:"request"`
+}
+
+// ContextOpenRequest mirrors AssetStudioFfiContextOpenRequest.
+type ContextOpenRequest struct {
+	InputPath         string   `json:"input_path"`
+	AssetTypes        []string `json:"asset_types"`
+	UnityVersion      *string  `json:"unity_version"`
+	FilterExcludeMode bool     `json:"filter_exclude_mode"`
+	FilterWithRegex   bool     `json:"filter_with_regex"`
+	FilterByName      *string  `json:"filter_by_name"`
+	FilterByContainer *string  `json:"filter_by_container"`
+	FilterByPathIDs   []int64  `json:"filter_by_path_ids"`
+	LoadAllAssets     bool     `json:"load_all_assets"`
+	IncludeAssets     bool     `json:"include_assets"`
+}
+
+func (ContextOpenRequest) Operation() string { return OperationContextOpen }
+
+// MarshalJSON encodes nil slices as [], since the Rust Vec fields reject null.
+func (r ContextOpenRequest) MarshalJSON() ([]byte, error) {
+	type plain ContextOpenRequest
+	r.AssetTypes = emptyIfNil(r.AssetTypes)
+	r.FilterByPathIDs = emptyIfNil(r.FilterByPathIDs)
+	return json.Marshal(plain(r))
+}
+
+// ContextListObjectsRequest mirrors AssetStudioFfiContextListObjectsRequest.
+type ContextListObjectsRequest struct {
+	ContextID int64 `json:"context_id"`
+	Offset    int   `json:"offset"`
+	Limit     int   `json:"limit"`
+}
+
+func (ContextListObjectsRequest) Operation() string { return OperationContextListObjects }
+
+// ContextCloseRequest mirrors AssetStudioFfiContextCloseRequest.
+type ContextCloseRequest struct {
+	ContextID int64 `json:"context_id"`
+}
+
+func (ContextCloseRequest) Operation() string { return OperationContextClose }
+
+// ContextReadObjectsRequest mirrors AssetStudioFfiContextReadObjectsRequest.
+type ContextReadObjectsRequest struct {
+	ContextID int64            `json:"context_id"`
+	Objects   []ReadObjectItem `json:"objects"`
+	// PayloadCapacityHint is the expected size of the packed payload block;
+	// above the worker's spill threshold it maps a spill file up front. 0
+	// keeps the in-memory path.
+	PayloadCapacityHint uint64 `json:"payload_capacity_hint"`
+}
+
+func (ContextReadObjectsRequest) Operation() string { return OperationContextReadObjects }
+
+// MarshalJSON encodes a nil Objects as [], since the Rust Vec rejects null.
+func (r ContextReadObjectsRequest) MarshalJSON() ([]byte, error) {
+	type plain ContextReadObjectsRequest
+	r.Objects = emptyIfNil(r.Objects)
+	return json.Marshal(plain(r))
+}
+
+// ReadObjectItem mirrors AssetStudioFfiContextReadObjectItemRequest.
+type ReadObjectItem struct {
+	PathID      int64  `json:"path_id"`
+	Kind        string `json:"kind"`
+	ImageFormat string `json:"image_format"`
+}
+
+// AssetInfo mirrors AssetStudioFfiAssetInfo.
+type AssetInfo struct {
+	Index      int     `json:"index"`
+	Name       *string `json:"name"`
+	Container  *string `json:"container"`
+	Type       *string `json:"type"`
+	TypeID     int32   `json:"type_id"`
+	PathID     int64   `json:"path_id"`
+	UniqueID   *string `json:"unique_id"`
+	Size       int64   `json:"size"`
+	SourceFile *string `json:"source_file"`
+}
+
+// OpenResponse mirrors AssetStudioFfiContextOpenResponse.
+type OpenResponse struct {
+	Success              bool              `json:"success"`
+	ContextID            int64             `json:"context_id"`
+	AssetsFileCount      int               `json:"assets_file_count"`
+	ExportableAssetCount int               `json:"exportable_asset_count"`
+	UnityVersion         *string           `json:"unity_version"`
+	Assets               []AssetInfo       `json:"assets"`
+	Warnings             []string          `json:"warnings"`
+	PhaseMS              map[string]uint64 `json:"phase_ms"`
+	Metrics              map[string]uint64 `json:"metrics"`
+	WorkerID             *string           `json:"worker_id"`
+	ObjectIndexCount     int               `json:"object_index_count"`
+	ReturnedAssetCount   int               `json:"returned_asset_count"`
+	HasMoreAssets        bool              `json:"has_more_assets"`
+	Error                *string           `json:"error"`
+	DurationMS           *uint64           `json:"duration_ms"`
+}
+
+// ListResponse mirrors AssetStudioFfiContextListObjectsResponse.
+type ListResponse struct {
+	Success       bool        `json:"success"`
+	ContextID     int64       `json:"context_id"`
+	Offset        int         `json:"offset"`
+	Limit         int         `json:"limit"`
+	NextOffset    *int        `json:"next_offset"`
+	TotalCount    int         `json:"total_count"`
+	ReturnedCount int         `json:"returned_count"`
+	Assets        []AssetInfo `json:"assets"`
+	Warnings      []string    `json:"warnings"`
+	Error         *string     `json:"error"`
+	DurationMS    *uint64     `json:"duration_ms"`
+}
+
+// CloseResponse mirrors AssetStudioFfiContextCloseResponse.
+type CloseResponse struct {
+	Success    bool     `json:"success"`
+	Warnings   []string `json:"warnings"`
+	Error      *string  `json:"error"`
+	DurationMS *uint64  `json:"duration_ms"`
+}
+
+// ReadResult mirrors AssetStudioFfiObjectReadResponse, one entry of a read
+// batch.
+type ReadResult struct {
+	Success            bool              `json:"success"`
+	Asset              *AssetInfo        `json:"asset"`
+	PayloadKind        *string           `json:"payload_kind"`
+	PayloadLen         int64             `json:"payload_len"`
+	SuggestedExtension *string           `json:"suggested_extension"`
+	Warnings           []string          `json:"warnings"`
+	PhaseMS            map[string]uint64 `json:"phase_ms"`
+	Error              *string           `json:"error"`
+	DurationMS         *uint64           `json:"duration_ms"`
+}
+
+// ReadBatchResponse mirrors AssetStudioFfiObjectReadBatchResponse.
+type ReadBatchResponse struct {
+	Success                 bool              `json:"success"`
+	Reads                   []ReadResult      `json:"reads"`
+	Warnings                []string          `json:"warnings"`
+	PhaseMS                 map[string]uint64 `json:"phase_ms"`
+	PayloadKindCounts       map[string]int    `json:"payload_kind_counts"`
+	PayloadBytesByKind      map[string]uint64 `json:"payload_bytes_by_kind"`
+	PayloadLen              int64             `json:"payload_len"`
+	ObjectCount             int               `json:"object_count"`
+	PayloadBundleVersion    uint32            `json:"payload_bundle_version"`
+	PayloadBundleEntryCount int               `json:"payload_bundle_entry_count"`
+	PayloadBundleBytes      int64             `json:"payload_bundle_bytes"`
+	PayloadDataBytes        uint64            `json:"payload_data_bytes"`
+	FailedCount             int               `json:"failed_count"`
+	ReadPayloadMS           uint64            `json:"read_payload_ms"`
+	Error                   *string           `json:"error"`
+	DurationMS              *uint64           `json:"duration_ms"`
+}
+
+func emptyIfNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// deref returns *p, or the zero value for a nil p.
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// goldenRequests are the Go values of the request frames in
+// testdata/protocol/*.request.json.
+var goldenRequests = map[string]Request{
+	OperationContextOpen: ContextOpenRequest{
+		InputPath:         "/data/cn/character/member/res020_no006",
+		AssetTypes:        []string{"Texture2D", "TextAsset"},
+		UnityVersion:      ptr("2022.3.21f1"),
+		FilterWithRegex:   true,
+		FilterByName:      ptr("^res020"),
+		FilterByPathIDs:   []int64{-4211316582546411137, 42},
+		LoadAllAssets:     true,
+		FilterExcludeMode: false,
+	},
+	OperationContextListObjects: ContextListObjectsRequest{ContextID: 7, Offset: 2048, Limit: 2048},
+	OperationContextClose:       ContextCloseRequest{ContextID: 7},
+	OperationContextReadObjects: ContextReadObjectsRequest{
+		ContextID: 7,
+		Objects: []ReadObjectItem{
+			{PathID: -4211316582546411137, Kind: "image", ImageFormat: "raw_rgba"},
+			{PathID: 5512, Kind: "text_bytes", ImageFormat: "png"},
+		},
+		PayloadCapacityHint: 4195540,
+	},
+}
+
+// responseTypes maps each operation to its response body type.
+var responseTypes = map[string]reflect.Type{
+	OperationContextOpen:        reflect.TypeFor[OpenResponse](),
+	OperationContextListObjects: reflect.TypeFor[ListResponse](),
+	OperationContextClose:       reflect.TypeFor[CloseResponse](),
+	OperationContextReadObjects: reflect.TypeFor[ReadBatchResponse](),
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func readGolden(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "protocol", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// decodeStrict decodes data into v, failing on fields v does not have.
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// assertSameJSON compares two JSON documents ignoring layout and key order.
+// Numbers are compared as written, so 64-bit path ids keep every digit.
+func assertSameJSON(t *testing.T, got, want []byte) {
+	t.Helper()
+	normalize := func(data []byte) any {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var v any
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	if !reflect.DeepEqual(normalize(got), normalize(want)) {
+		t.Errorf("JSON differs from golden\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestRequestsMatchGoldenFrames(t *testing.T) {
+	for operation, request := range goldenRequests {
+		t.Run(operation, func(t *testing.T) {
+			if request.Operation() != operation {
+				t.Fatalf("%T.Operation() = %q", request, request.Operation())
+			}
+			golden := readGolden(t, operation+".request.json")
+			got, err := json.Marshal(taggedRequest{Operation: operation, Request: request})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameJSON(t, got, golden)
+
+			var frame struct {
+				Operation string          `json:"operation"`
+				Request   json.RawMessage `json:"request"`
+			}
+			if err := decodeStrict(golden, &frame); err != nil {
+				t.Fatal(err)
+			}
+			decoded := reflect.New(reflect.TypeOf(request))
+			if err := decodeStrict(frame.Request, decoded.Interface()); err != nil {
+				t.Fatalf("golden request has fields %T lacks: %v", request, err)
+			}
+			if !reflect.DeepEqual(decoded.Elem().Interface(), request) {
+				t.Errorf("decoded golden = %+v, want %+v", decoded.Elem().Interface(), request)
+			}
+		})
+	}
+}
+
+func TestNilRequestSlicesEncodeAsEmptyArrays(t *testing.T) {
+	for _, tc := range []struct {
+		request Request
+		fields  []string
+	}{
+		{ContextOpenRequest{}, []string{"asset_types", "filter_by_path_ids"}},
+		{ContextReadObjectsRequest{}, []string{"objects"}},
+	} {
+		request := tc.request
+		data, err := json.Marshal(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]json.RawMessage
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range tc.fields {
+			if string(got[field]) != "[]" {
+				t.Errorf("%T encodes nil %s as %s, want []", request, field, got[field])
+			}
+		}
+	}
+}
+
+func TestResponsesMatchGoldenFrames(t *testing.T) {
+	for operation, bodyType := range responseTypes {
+		t.Run(operation, func(t *testing.T) {
+			golden := readGolden(t, operation+".response.json")
+			var frame OperationResult
+			if err := decodeStrict(golden, &frame); err != nil {
+				t.Fatal(err)
+			}
+			if frame.Operation != operation {
+				t.Fatalf("golden operation = %q", frame.Operation)
+			}
+			body := reflect.New(bodyType).Interface()
+			if err := decodeStrict(frame.Response, body); err != nil {
+				t.Fatalf("golden response has fields %s lacks: %v", bodyType, err)
+			}
+			got, err := json.Marshal(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameJSON(t, got, frame.Response)
+		})
+	}
+}
+
+// rustProtocolTypes maps the serde structs in types.rs to their Go mirrors.
+var rustProtocolTypes = map[string]reflect.Type{
+	"AssetStudioFfiContextOpenRequest":           reflect.TypeFor[ContextOpenRequest](),
+	"AssetStudioFfiContextListObjectsRequest":    reflect.TypeFor[ContextListObjectsRequest](),
+	"AssetStudioFfiContextCloseRequest":          reflect.TypeFor[ContextCloseRequest](),
+	"AssetStudioFfiContextReadObjectsRequest":    reflect.TypeFor[ContextReadObjectsRequest](),
+	"AssetStudioFfiContextReadObjectItemRequest": reflect.TypeFor[ReadObjectItem](),
+	"AssetStudioFfiAssetInfo":                    reflect.TypeFor[AssetInfo](),
+	"AssetStudioFfiContextOpenResponse":          reflect.TypeFor[OpenResponse](),
+	"AssetStudioFfiContextListObjectsResponse":   reflect.TypeFor[ListResponse](),
+	"AssetStudioFfiContextCloseResponse":         reflect.TypeFor[CloseResponse](),
+	"AssetStudioFfiObjectReadResponse":           reflect.TypeFor[ReadResult](),
+	"AssetStudioFfiObjectReadBatchResponse":      reflect.TypeFor[ReadBatchResponse](),
+}
+
+var (
+	serdeStructPattern  = regexp.MustCompile(`(?s)#\[derive\([^)]*Serialize[^)]*\)\]\s*pub struct (\w+) \{(.*?)\n\}`)
+	serdeEnumPattern    = regexp.MustCompile(`(?s)pub enum (AssetStudioFfiRequest|AssetStudioFfiResponse) \{(.*?)\n\}`)
+	serdeFieldPattern   = regexp.MustCompile(`^pub (\w+): (.+),$`)
+	serdeRenamePattern  = regexp.MustCompile(`#\[serde\(rename = "(\w+)"\)\]`)
+	serdeVariantPattern = regexp.MustCompile(`^(\w+)\((\w+)\),$`)
+	rustIntegerPattern  = regexp.MustCompile(`^[iu](8|16|32|64|size)$`)
+)
+
+// shapeOfRust classifies a Rust field type the way shapeOfGo classifies its
+// Go mirror, so an Option becoming required (or the reverse) is caught.
+func shapeOfRust(rustType string) string {
+	switch {
+	case strings.HasPrefix(rustType, "Option<"):
+		return "option"
+	case strings.HasPrefix(rustType, "Vec<"):
+		return "list"
+	case strings.HasPrefix(rustType, "HashMap<"):
+		return "map"
+	case rustType == "bool":
+		return "bool"
+	case rustType == "String":
+		return "string"
+	case rustIntegerPattern.MatchString(rustType):
+		return "integer"
+	}
+	return "unknown " + rustType
+}
+
+func shapeOfGo(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "option"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		return "map"
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	}
+	return "unknown " + t.String()
+}
+
+type protocolField struct {
+	name  string
+	shape string
+}
+
+func goProtocolFields(t reflect.Type) []protocolField {
+	var fields []protocolField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		fields = append(fields, protocolField{name: name, shape: shapeOfGo(f.Type)})
+	}
+	return fields
+}
+
+func TestProtocolTypesMatchRustDefinitions(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("..", "..", "..", "crates", "assetstudio-ffi", "src", "types.rs"))
+	if err != nil {
+		t.Skipf("Rust sources not available: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, m := range serdeStructPattern.FindAllStringSubmatch(string(source), -1) {
+		goType, found := rustProtocolTypes[m[1]]
+		if !found {
+			t.Errorf("types.rs has serde struct %s with no Go mirror", m[1])
+			continue
+		}
+		seen[m[1]] = true
+		var rustFields []protocolField
+		rename := ""
+		for _, line := range strings.Split(m[2], "\n") {
+			line = strings.TrimSpace(line)
+			if r := serdeRenamePattern.FindStringSubmatch(line); r != nil {
+				rename = r[1]
+			}
+			f := serdeFieldPattern.FindStringSubmatch(line)
+			if f == nil {
+				continue
+			}
+			name := f[1]
+			if rename != "" {
+				name, rename = rename, ""
+			}
+			rustFields = append(rustFields, protocolField{name: name, shape: shapeOfRust(f[2])})
+		}
+		if goFields := goProtocolFields(goType); !reflect.DeepEqual(goFields, rustFields) {
+			t.Errorf("%s fields drifted from %s\n  Go: %v\nRust: %v", goType.Name(), m[1], goFields, rustFields)
+		}
+	}
+	for name := range rustProtocolTypes {
+		if !seen[name] {
+			t.Errorf("types.rs no longer defines serde struct %s", name)
+		}
+	}
+
+	for _, m := range serdeEnumPattern.FindAllStringSubmatch(string(source), -1) {
+		for _, line := range strings.Split(m[2], "\n") {
+			v := serdeVariantPattern.FindStringSubmatch(strings.TrimSpace(line))
+			if v == nil {
+				continue
+			}
+			operation := snakeCase(v[1])
+			switch m[1] {
+			case "AssetStudioFfiRequest":
+				request, found := goldenRequests[operation]
+				if !found || reflect.TypeOf(request) != rustProtocolTypes[v[2]] {
+					t.Errorf("AssetStudioFfiRequest::%s(%s) has no matching Go request for %q", v[1], v[2], operation)
+				}
+			case "AssetStudioFfiResponse":
+				if responseTypes[operation] != rustProtocolTypes[v[2]] {
+					t.Errorf("AssetStudioFfiResponse::%s(%s) has no matching Go response for %q", v[1], v[2], operation)
+				}
+			}
+		}
+	}
+}
+
+// snakeCase renames a Rust variant the way serde's rename_all = "snake_case"
+// does.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if 'A' <= r && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,317 @@
+package assetstudio
+
+// Go mirrors of the structs in include/haruki_assetstudio_native.h, in
+// cgo -godefs style (C field names with the first letter capitalised). Both
+// backends pass pointers to these straight to the native library, so field
+// order and types must match the header exactly;
+// TestGoMirrorsMatchVendoredHeader checks that.
+
+// capabilitiesResponse mirrors haruki_assetstudio_capabilities_response.
+type capabilitiesResponse struct {
+	Struct_size                                    int32
+	Abi_version                                    int32
+	Schema_version                                 int32
+	Status                                         int32
+	Error_code                                     int32
+	Core_api_version_major                         int32
+	Core_api_version_minor                         int32
+	Context_abi_version                            int32
+	Object_table_abi_version                       int32
+	Object_table_into_abi_version                  int32
+	Object_lookup_abi_version                      int32
+	Object_lookup_into_abi_version                 int32
+	Object_read_abi_version                        int32
+	Object_read_batch_abi_version                  int32
+	Object_read_batch_handle_abi_version           int32
+	Object_read_batch_into_abi_version             int32
+	Object_read_batch_by_index_abi_version         int32
+	Object_read_batch_direct_into_abi_version      int32
+	Object_read_batch_direct_retry_abi_version     int32
+	Supports_typed_object_table                    int32
+	Supports_caller_provided_object_table_buffers  int32
+	Supports_typed_object_lookup                   int32
+	Supports_caller_provided_object_lookup_buffers int32
+	Supports_typed_object_read                     int32
+	Supports_typed_object_read_batch               int32
+	Supports_result_handle                         int32
+	Supports_direct_object_read_retry              int32
+	Supports_typed_context                         int32
+	Supports_native_dependency_resolver            int32
+	Supports_abi_layout                            int32
+	Supports_multiple_contexts                     int32
+	Supports_concurrent_operations                 int32
+	Supports_context_lifetime_guards               int32
+	Native_console_capture                         int32
+	Flags                                          int32
+	Reserved                                       int32
+}
+
+// abiLayoutResponse mirrors haruki_assetstudio_abi_layout_response.
+type abiLayoutResponse struct {
+	Struct_size                         int32
+	Abi_version                         int32
+	Schema_version                      int32
+	Status                              int32
+	Error_code                          int32
+	Layout_version                      int32
+	Context_open_request                int32
+	Context_open_response               int32
+	Context_close_request               int32
+	Context_close_response              int32
+	Limits_response                     int32
+	Capabilities_response               int32
+	Object_list_request                 int32
+	Object_list_into_request_v1         int32
+	Object_table                        int32
+	Asset_object                        int32
+	Object_read_item_request            int32
+	Object_read_batch_into_request_v1   int32
+	Object_read_item_response_v1        int32
+	Object_read_batch_retry_response_v1 int32
+	Flags                               int32
+	Reserved                            int32
+}
+
+// limitsResponse mirrors haruki_assetstudio_limits_response.
+type limitsResponse struct {
+	Struct_size                                int32
+	Abi_version                                int32
+	Schema_version                             int32
+	Limits_abi_version                         int32
+	Status                                     int32
+	Error_code                                 int32
+	Max_native_utf8_bytes                      int32
+	Max_object_read_batch_count                int32
+	Max_object_table_page_limit                int32
+	Max_object_read_batch_payload_bytes        int64
+	Max_cached_object_read_batch_payload_bytes int64
+	Max_active_contexts                        int32
+	Max_concurrent_operations                  int32
+	Supports_multiple_contexts                 int32
+	Supports_concurrent_operations             int32
+	Legacy_static_engine                       int32
+	Native_console_capture                     int32
+	Flags                                      int32
+	Reserved                                   int32
+}
+
+// contextOpenRequest mirrors haruki_assetstudio_context_open_request.
+type contextOpenRequest struct {
+	Struct_size              int32
+	Input_path_utf8          *byte
+	Input_path_utf8_len      int32
+	Unity_version_utf8       *byte
+	Unity_version_utf8_len   int32
+	Asset_types_csv_utf8     *byte
+	Asset_types_csv_utf8_len int32
+	Output_dir_utf8          *byte
+	Output_dir_utf8_len      int32
+	Load_all_assets          int32
+	Flags                    int32
+	Reserved                 int32
+}
+
+// contextOpenResponse mirrors haruki_assetstudio_context_open_response.
+type contextOpenResponse struct {
+	Struct_size            int32
+	Abi_version            int32
+	Schema_version         int32
+	Context_abi_version    int32
+	Status                 int32
+	Error_code             int32
+	Context_id             int64
+	Assets_file_count      int32
+	Exportable_asset_count int32
+	Object_index_count     int32
+	Has_more_assets        int32
+	Unity_version_utf8     *byte
+	Unity_version_utf8_len int32
+	Buffer                 *byte
+	Buffer_len             int64
+	Duration_ms            int64
+	Flags                  int32
+	Reserved               int32
+}
+
+// contextCloseRequest mirrors haruki_assetstudio_context_close_request.
+type contextCloseRequest struct {
+	Struct_size int32
+	Context_id  int64
+	Flags       int32
+	Reserved    int32
+}
+
+// contextCloseResponse mirrors haruki_assetstudio_context_close_response.
+type contextCloseResponse struct {
+	Struct_size         int32
+	Abi_version         int32
+	Schema_version      int32
+	Context_abi_version int32
+	Status              int32
+	Error_code          int32
+	Context_id          int64
+	Duration_ms         int64
+	Flags               int32
+	Reserved            int32
+}
+
+// objectListRequest mirrors haruki_assetstudio_object_list_request.
+type objectListRequest struct {
+	Struct_size              int32
+	Context_id               int64
+	Offset                   int32
+	Limit                    int32
+	Asset_types_csv_utf8     *byte
+	Asset_types_csv_utf8_len int32
+	Flags                    int32
+	Reserved                 int32
+}
+
+// objectListIntoRequestV1 mirrors haruki_assetstudio_object_list_into_request_v1.
+type objectListIntoRequestV1 struct {
+	Struct_size              int32
+	Context_id               int64
+	Offset                   int32
+	Limit                    int32
+	Asset_types_csv_utf8     *byte
+	Asset_types_csv_utf8_len int32
+	Flags                    int32
+	Reserved                 int32
+	Buffer                   *byte
+	Buffer_len               int64
+}
+
+// assetObject mirrors haruki_assetstudio_asset_object.
+type assetObject struct {
+	Index                      int32
+	Type_id                    int32
+	Path_id                    int64
+	Size                       int64
+	Estimated_payload_capacity int64
+	Raw_payload_capacity       int64
+	Image_payload_capacity     int64
+	Text_payload_capacity      int64
+	Payload_capacity_flags     int32
+	Reserved                   int32
+	Name_offset                int32
+	Name_len                   int32
+	Container_offset           int32
+	Container_len              int32
+	Type_offset                int32
+	Type_len                   int32
+	Unique_id_offset           int32
+	Unique_id_len              int32
+	Source_file_offset         int32
+	Source_file_len            int32
+}
+
+// objectTable mirrors haruki_assetstudio_object_table.
+type objectTable struct {
+	Struct_size              int32
+	Abi_version              int32
+	Schema_version           int32
+	Object_table_abi_version int32
+	Status                   int32
+	Error_code               int32
+	Context_id               int64
+	Offset                   int32
+	Limit                    int32
+	Next_offset              int32
+	Has_more                 int32
+	Total_count              int32
+	Returned_count           int32
+	Objects                  *assetObject
+	String_data              *byte
+	String_data_len          int32
+	Buffer                   *byte
+	Buffer_len               int64
+	Duration_ms              int64
+	Flags                    int32
+	Reserved                 int32
+}
+
+// objectReadItemRequest mirrors haruki_assetstudio_object_read_item_request.
+type objectReadItemRequest struct {
+	Path_id               int64
+	Kind_utf8             *byte
+	Kind_utf8_len         int32
+	Image_format_utf8     *byte
+	Image_format_utf8_len int32
+}
+
+// objectReadBatchIntoRequestV1 mirrors haruki_assetstudio_object_read_batch_into_request_v1.
+type objectReadBatchIntoRequestV1 struct {
+	Struct_size      int32
+	Context_id       int64
+	Items            *objectReadItemRequest
+	Count            int32
+	Flags            int32
+	Items_buffer     *byte
+	Items_buffer_len int64
+	Payload          *byte
+	Payload_len      int64
+	Reserved         int32
+}
+
+// objectReadItemResponseV1 mirrors haruki_assetstudio_object_read_item_response_v1.
+type objectReadItemResponseV1 struct {
+	Index                      int32
+	Status                     int32
+	Error_code                 int32
+	Path_id                    int64
+	Type_id                    int32
+	Size                       int64
+	Payload_offset             int64
+	Payload_len                int64
+	Payload_kind_offset        int32
+	Payload_kind_len           int32
+	Suggested_extension_offset int32
+	Suggested_extension_len    int32
+	Error_message_offset       int32
+	Error_message_len          int32
+}
+
+// objectReadBatchRetryResponseV1 mirrors haruki_assetstudio_object_read_batch_retry_response_v1.
+type objectReadBatchRetryResponseV1 struct {
+	Struct_size                                int32
+	Abi_version                                int32
+	Schema_version                             int32
+	Object_read_batch_abi_version              int32
+	Object_read_batch_into_abi_version         int32
+	Object_read_batch_direct_retry_abi_version int32
+	Status                                     int32
+	Error_code                                 int32
+	Context_id                                 int64
+	Requested_count                            int32
+	Returned_count                             int32
+	Failed_count                               int32
+	Items                                      *objectReadItemResponseV1
+	String_data                                *byte
+	String_data_len                            int32
+	Items_buffer                               *byte
+	Items_buffer_len                           int64
+	Payload                                    *byte
+	Payload_len                                int64
+	Required_items_buffer_len                  int64
+	Required_string_data_len                   int32
+	Required_payload_len                       int64
+	Duration_ms                                int64
+	Result_handle                              int64
+	Ownership_flags                            int32
+	Flags                                      int32
+	Reserved                                   int32
+}
+
+// Exported symbols both backends resolve at load time.
+const (
+	symCapabilities = "haruki_assetstudio_capabilities_v1"
+	symABILayout    = "haruki_assetstudio_abi_layout_v1"
+	symLimits       = "haruki_assetstudio_limits_v1"
+	symContextOpen  = "haruki_assetstudio_context_open_v1"
+	symListSize     = "haruki_assetstudio_context_list_objects_size_v1"
+	symListInto     = "haruki_assetstudio_context_list_objects_into_v1"
+	symReadRetry    = "haruki_assetstudio_context_read_objects_direct_retry_v1"
+	symContextClose = "haruki_assetstudio_context_close_v1"
+	symResultFree   = "haruki_assetstudio_result_free"
+	symFreeBuffer   = "haruki_assetstudio_free_buffer"
+)
@@ -0,0 +1,100 @@
+package assetstudio
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var goMirrors = map[string]reflect.Type{
+	"haruki_assetstudio_capabilities_response":               reflect.TypeFor[capabilitiesResponse](),
+	"haruki_assetstudio_abi_layout_response":                 reflect.TypeFor[abiLayoutResponse](),
+	"haruki_assetstudio_limits_response":                     reflect.TypeFor[limitsResponse](),
+	"haruki_assetstudio_context_open_request":                reflect.TypeFor[contextOpenRequest](),
+	"haruki_assetstudio_context_open_response":               reflect.TypeFor[contextOpenResponse](),
+	"haruki_assetstudio_context_close_request":               reflect.TypeFor[contextCloseRequest](),
+	"haruki_assetstudio_context_close_response":              reflect.TypeFor[contextCloseResponse](),
+	"haruki_assetstudio_object_list_request":                 reflect.TypeFor[objectListRequest](),
+	"haruki_assetstudio_object_list_into_request_v1":         reflect.TypeFor[objectListIntoRequestV1](),
+	"haruki_assetstudio_asset_object":                        reflect.TypeFor[assetObject](),
+	"haruki_assetstudio_object_table":                        reflect.TypeFor[objectTable](),
+	"haruki_assetstudio_object_read_item_request":            reflect.TypeFor[objectReadItemRequest](),
+	"haruki_assetstudio_object_read_batch_into_request_v1":   reflect.TypeFor[objectReadBatchIntoRequestV1](),
+	"haruki_assetstudio_object_read_item_response_v1":        reflect.TypeFor[objectReadItemResponseV1](),
+	"haruki_assetstudio_object_read_batch_retry_response_v1": reflect.TypeFor[objectReadBatchRetryResponseV1](),
+}
+
+// goMirrorType is the Go type abi.go must use for a header field type.
+func goMirrorType(t *testing.T, cType string) reflect.Type {
+	cType = strings.TrimPrefix(cType, "const ")
+	switch cType {
+	case "int32_t":
+		return reflect.TypeFor[int32]()
+	case "int64_t":
+		return reflect.TypeFor[int64]()
+	case "uint8_t*":
+		return reflect.TypeFor[*byte]()
+	}
+	if mirror, found := goMirrors[strings.TrimSuffix(cType, "*")]; found && strings.HasSuffix(cType, "*") {
+		return reflect.PointerTo(mirror)
+	}
+	t.Fatalf("header uses field type %q that the Go mirrors do not know", cType)
+	return nil
+}
+
+// cLayout returns the field offsets and size the C compiler gives a struct of
+// naturally aligned scalars and pointers on a 64-bit target.
+func cLayout(types []reflect.Type) ([]uintptr, uintptr) {
+	var offset, align uintptr = 0, 1
+	offsets := make([]uintptr, len(types))
+	for i, ft := range types {
+		a := ft.Size()
+		offset = (offset + a - 1) / a * a
+		offsets[i] = offset
+		offset += ft.Size()
+		align = max(align, a)
+	}
+	return offsets, (offset + align - 1) / align * align
+}
+
+func TestGoMirrorsMatchVendoredHeader(t *testing.T) {
+	header, err := os.ReadFile(filepath.Join("include", "haruki_assetstudio_native.h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := parseHeaderStructs(t, string(header))
+	if len(c) != len(goMirrors) {
+		t.Errorf("header defines %d structs, abi.go mirrors %d", len(c), len(goMirrors))
+	}
+	for name, cFields := range c {
+		mirror, found := goMirrors[name]
+		if !found {
+			t.Errorf("abi.go has no mirror for %s", name)
+			continue
+		}
+		if mirror.NumField() != len(cFields) {
+			t.Errorf("%s: header has %d fields, %s has %d", name, len(cFields), mirror.Name(), mirror.NumField())
+			continue
+		}
+		types := make([]reflect.Type, len(cFields))
+		for i, cf := range cFields {
+			gf := mirror.Field(i)
+			want := strings.ToUpper(cf.name[:1]) + cf.name[1:]
+			types[i] = goMirrorType(t, cf.cType)
+			if gf.Name != want || gf.Type != types[i] {
+				t.Errorf("%s field %d: header %s %s, %s has %s %s", name, i, cf.cType, cf.name, mirror.Name(), gf.Type, gf.Name)
+			}
+		}
+		offsets, size := cLayout(types)
+		for i, off := range offsets {
+			if got := mirror.Field(i).Offset; got != off {
+				t.Errorf("%s.%s: Go offset %d, C offset %d", mirror.Name(), mirror.Field(i).Name, got, off)
+			}
+		}
+		if mirror.Size() != size {
+			t.Errorf("%s: Go size %d, C size %d", mirror.Name(), mirror.Size(), size)
+		}
+	}
+}
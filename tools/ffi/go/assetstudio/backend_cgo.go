@@ -0,0 +1,149 @@
+//go:build cgo && !purego
+
+package assetstudio
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/include
+#cgo linux LDFLAGS: -ldl
+#include <stdint.h>
+#include <stdlib.h>
+#include <dlfcn.h>
+#include "haruki_assetstudio_native.h"
+
+typedef int32_t (*capabilities_fn)(haruki_assetstudio_capabilities_response*);
+typedef int32_t (*abi_layout_fn)(haruki_assetstudio_abi_layout_response*);
+typedef int32_t (*limits_fn)(haruki_assetstudio_limits_response*);
+typedef int32_t (*context_open_fn)(const haruki_assetstudio_context_open_request*, haruki_assetstudio_context_open_response*);
+typedef int32_t (*list_size_fn)(const haruki_assetstudio_object_list_request*, haruki_assetstudio_object_table*);
+typedef int32_t (*list_into_fn)(const haruki_assetstudio_object_list_into_request_v1*, haruki_assetstudio_object_table*);
+typedef int32_t (*read_retry_fn)(const haruki_assetstudio_object_read_batch_into_request_v1*, haruki_assetstudio_object_read_batch_retry_response_v1*);
+typedef int32_t (*context_close_fn)(const haruki_assetstudio_context_close_request*, haruki_assetstudio_context_close_response*);
+typedef int32_t (*result_free_fn)(int64_t);
+typedef void (*free_buffer_fn)(uint8_t*);
+
+static int32_t call_capabilities(void* f, haruki_assetstudio_capabilities_response* r) { return ((capabilities_fn)f)(r); }
+static int32_t call_abi_layout(void* f, haruki_assetstudio_abi_layout_response* r) { return ((abi_layout_fn)f)(r); }
+static int32_t call_limits(void* f, haruki_assetstudio_limits_response* r) { return ((limits_fn)f)(r); }
+static int32_t call_context_open(void* f, const haruki_assetstudio_context_open_request* q, haruki_assetstudio_context_open_response* r) { return ((context_open_fn)f)(q, r); }
+static int32_t call_list_size(void* f, const haruki_assetstudio_object_list_request* q, haruki_assetstudio_object_table* r) { return ((list_size_fn)f)(q, r); }
+static int32_t call_list_into(void* f, const haruki_assetstudio_object_list_into_request_v1* q, haruki_assetstudio_object_table* r) { return ((list_into_fn)f)(q, r); }
+static int32_t call_read_retry(void* f, const haruki_assetstudio_object_read_batch_into_request_v1* q, haruki_assetstudio_object_read_batch_retry_response_v1* r) { return ((read_retry_fn)f)(q, r); }
+static int32_t call_context_close(void* f, const haruki_assetstudio_context_close_request* q, haruki_assetstudio_context_close_response* r) { return ((context_close_fn)f)(q, r); }
+static int32_t call_result_free(void* f, int64_t h) { return ((result_free_fn)f)(h); }
+static void call_free_buffer(void* f, uint8_t* p) { ((free_buffer_fn)f)(p); }
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Backend names the native loader this package was built with: "cgo" or
+// "purego".
+const Backend = "cgo"
+
+// backend calls the library through cgo trampolines. The Go mirrors in abi.go
+// are passed by pointer and reinterpreted as the header's C structs.
+type backend struct {
+	handle         unsafe.Pointer
+	capabilitiesFn unsafe.Pointer
+	abiLayoutFn    unsafe.Pointer
+	limitsFn       unsafe.Pointer
+	contextOpenFn  unsafe.Pointer
+	listSizeFn     unsafe.Pointer
+	listIntoFn     unsafe.Pointer
+	readRetryFn    unsafe.Pointer
+	contextCloseFn unsafe.Pointer
+	resultFreeFn   unsafe.Pointer
+	freeBufferFn   unsafe.Pointer
+}
+
+func dlerror() string {
+	if msg := C.dlerror(); msg != nil {
+		return C.GoString(msg)
+	}
+	return "unknown error"
+}
+
+// preloadLibrary dlopens a native dependency with RTLD_GLOBAL so the main
+// library can resolve it.
+func preloadLibrary(path string) error {
+	cp := C.CString(path)
+	defer C.free(unsafe.Pointer(cp))
+	if C.dlopen(cp, C.RTLD_NOW|C.RTLD_GLOBAL) == nil {
+		return fmt.Errorf("dlopen %s failed: %s", path, dlerror())
+	}
+	return nil
+}
+
+func openBackend(path string) (*backend, error) {
+	cp := C.CString(path)
+	h := C.dlopen(cp, C.RTLD_NOW|C.RTLD_GLOBAL)
+	C.free(unsafe.Pointer(cp))
+	if h == nil {
+		return nil, errors.New("dlopen failed: " + dlerror())
+	}
+	b := &backend{handle: h}
+	for name, dst := range map[string]*unsafe.Pointer{
+		symCapabilities: &b.capabilitiesFn,
+		symABILayout:    &b.abiLayoutFn,
+		symLimits:       &b.limitsFn,
+		symContextOpen:  &b.contextOpenFn,
+		symListSize:     &b.listSizeFn,
+		symListInto:     &b.listIntoFn,
+		symReadRetry:    &b.readRetryFn,
+		symContextClose: &b.contextCloseFn,
+		symResultFree:   &b.resultFreeFn,
+		symFreeBuffer:   &b.freeBufferFn,
+	} {
+		cs := C.CString(name)
+		*dst = C.dlsym(h, cs)
+		C.free(unsafe.Pointer(cs))
+		if *dst == nil {
+			return nil, fmt.Errorf("missing symbol %s", name)
+		}
+	}
+	return b, nil
+}
+
+func (b *backend) capabilities(r *capabilitiesResponse) int32 {
+	return int32(C.call_capabilities(b.capabilitiesFn, (*C.haruki_assetstudio_capabilities_response)(unsafe.Pointer(r))))
+}
+
+func (b *backend) abiLayout(r *abiLayoutResponse) int32 {
+	return int32(C.call_abi_layout(b.abiLayoutFn, (*C.haruki_assetstudio_abi_layout_response)(unsafe.Pointer(r))))
+}
+
+func (b *backend) limits(r *limitsResponse) int32 {
+	return int32(C.call_limits(b.limitsFn, (*C.haruki_assetstudio_limits_response)(unsafe.Pointer(r))))
+}
+
+func (b *backend) contextOpen(q *contextOpenRequest, r *contextOpenResponse) int32 {
+	return int32(C.call_context_open(b.contextOpenFn, (*C.haruki_assetstudio_context_open_request)(unsafe.Pointer(q)), (*C.haruki_assetstudio_context_open_response)(unsafe.Pointer(r))))
+}
+
+func (b *backend) listSize(q *objectListRequest, r *objectTable) int32 {
+	return int32(C.call_list_size(b.listSizeFn, (*C.haruki_assetstudio_object_list_request)(unsafe.Pointer(q)), (*C.haruki_assetstudio_object_table)(unsafe.Pointer(r))))
+}
+
+func (b *backend) listInto(q *objectListIntoRequestV1, r *objectTable) int32 {
+	return int32(C.call_list_into(b.listIntoFn, (*C.haruki_assetstudio_object_list_into_request_v1)(unsafe.Pointer(q)), (*C.haruki_assetstudio_object_table)(unsafe.Pointer(r))))
+}
+
+func (b *backend) readRetry(q *objectReadBatchIntoRequestV1, r *objectReadBatchRetryResponseV1) int32 {
+	return int32(C.call_read_retry(b.readRetryFn, (*C.haruki_assetstudio_object_read_batch_into_request_v1)(unsafe.Pointer(q)), (*C.haruki_assetstudio_object_read_batch_retry_response_v1)(unsafe.Pointer(r))))
+}
+
+func (b *backend) contextClose(q *contextCloseRequest, r *contextCloseResponse) int32 {
+	return int32(C.call_context_close(b.contextCloseFn, (*C.haruki_assetstudio_context_close_request)(unsafe.Pointer(q)), (*C.haruki_assetstudio_context_close_response)(unsafe.Pointer(r))))
+}
+
+func (b *backend) resultFree(handle int64) int32 {
+	return int32(C.call_result_free(b.resultFreeFn, C.int64_t(handle)))
+}
+
+func (b *backend) freeBuffer(p *byte) {
+	C.call_free_buffer(b.freeBufferFn, (*C.uint8_t)(unsafe.Pointer(p)))
+}
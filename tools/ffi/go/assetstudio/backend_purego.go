@@ -0,0 +1,66 @@
+//go:build !cgo || purego
+
+package assetstudio
+
+import (
+	"fmt"
+
+	"github.com/ebitengine/purego"
+)
+
+// Backend names the native loader this package was built with: "cgo" or
+// "purego".
+const Backend = "purego"
+
+// backend calls the library through purego, so the binary builds with
+// CGO_ENABLED=0 and needs no C toolchain. The Go mirrors in abi.go are passed
+// by pointer exactly as the cgo backend passes the header's structs.
+type backend struct {
+	handle       uintptr
+	capabilities func(*capabilitiesResponse) int32
+	abiLayout    func(*abiLayoutResponse) int32
+	limits       func(*limitsResponse) int32
+	contextOpen  func(*contextOpenRequest, *contextOpenResponse) int32
+	listSize     func(*objectListRequest, *objectTable) int32
+	listInto     func(*objectListIntoRequestV1, *objectTable) int32
+	readRetry    func(*objectReadBatchIntoRequestV1, *objectReadBatchRetryResponseV1) int32
+	contextClose func(*contextCloseRequest, *contextCloseResponse) int32
+	resultFree   func(int64) int32
+	freeBuffer   func(*byte)
+}
+
+// preloadLibrary dlopens a native dependency with RTLD_GLOBAL so the main
+// library can resolve it.
+func preloadLibrary(path string) error {
+	if _, err := purego.Dlopen(path, purego.RTLD_NOW|purego.RTLD_GLOBAL); err != nil {
+		return fmt.Errorf("dlopen %s failed: %w", path, err)
+	}
+	return nil
+}
+
+func openBackend(path string) (*backend, error) {
+	h, err := purego.Dlopen(path, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		return nil, fmt.Errorf("dlopen failed: %w", err)
+	}
+	b := &backend{handle: h}
+	for name, fptr := range map[string]any{
+		symCapabilities: &b.capabilities,
+		symABILayout:    &b.abiLayout,
+		symLimits:       &b.limits,
+		symContextOpen:  &b.contextOpen,
+		symListSize:     &b.listSize,
+		symListInto:     &b.listInto,
+		symReadRetry:    &b.readRetry,
+		symContextClose: &b.contextClose,
+		symResultFree:   &b.resultFree,
+		symFreeBuffer:   &b.freeBuffer,
+	} {
+		sym, err := purego.Dlsym(h, name)
+		if err != nil {
+			return nil, fmt.Errorf("missing symbol %s", name)
+		}
+		purego.RegisterFunc(fptr, sym)
+	}
+	return b, nil
+}
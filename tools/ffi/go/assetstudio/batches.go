@@ -0,0 +1,58 @@
+package assetstudio
+
+// Fallback payload estimates when the object table has no capacity for an
+// item, matching native_object_read_payload_capacity_hint in the Rust export
+// pipeline: decoded images can grow ~16x over their compressed source, other
+// kinds stay close to it.
+const (
+	imageCapacitySlack = 1 << 20
+	otherCapacitySlack = 64 << 10
+)
+
+// readCapacity is the payload size to plan for when reading asset as kind.
+func readCapacity(asset AssetInfo, kind string) int64 {
+	if kind == "image" && asset.ImagePayloadCapacity > 0 {
+		return asset.ImagePayloadCapacity
+	}
+	if asset.EstimatedPayloadCapacity > 0 {
+		return asset.EstimatedPayloadCapacity
+	}
+	size := max(asset.Size, 0)
+	if kind == "image" {
+		return size*16 + imageCapacitySlack
+	}
+	return size*2 + otherCapacitySlack
+}
+
+// planReadBatches splits items into consecutive sub-batches that respect the
+// native per-batch count and payload limits. A limit <= 0 is treated as
+// unlimited. An item whose capacity alone exceeds maxPayload still gets a
+// batch of its own, so the native side can report it as a per-item failure.
+func planReadBatches(items []ReadItem, maxCount int, maxPayload int64) [][]ReadItem {
+	var batches [][]ReadItem
+	start := 0
+	var payload int64
+	for i, it := range items {
+		n := i - start
+		full := maxCount > 0 && n >= maxCount
+		over := maxPayload > 0 && n > 0 && payload+it.PayloadCapacity > maxPayload
+		if full || over {
+			batches = append(batches, items[start:i])
+			start = i
+			payload = 0
+		}
+		payload += it.PayloadCapacity
+	}
+	if start < len(items) {
+		batches = append(batches, items[start:])
+	}
+	return batches
+}
+
+func batchPayloadCapacity(items []ReadItem) int64 {
+	var total int64
+	for _, it := range items {
+		total += it.PayloadCapacity
+	}
+	return total
+}
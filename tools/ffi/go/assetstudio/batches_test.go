@@ -0,0 +1,72 @@
+package assetstudio
+
+import "testing"
+
+func batchPathIDs(batches [][]ReadItem) [][]int64 {
+	out := make([][]int64, len(batches))
+	for i, b := range batches {
+		for _, it := range b {
+			out[i] = append(out[i], it.PathID)
+		}
+	}
+	return out
+}
+
+func TestPlanReadBatches(t *testing.T) {
+	items := []ReadItem{
+		{PathID: 1, PayloadCapacity: 40},
+		{PathID: 2, PayloadCapacity: 40},
+		{PathID: 3, PayloadCapacity: 40},
+		{PathID: 4, PayloadCapacity: 200},
+		{PathID: 5, PayloadCapacity: 10},
+	}
+	cases := []struct {
+		name       string
+		maxCount   int
+		maxPayload int64
+		want       [][]int64
+	}{
+		{"unlimited", 0, 0, [][]int64{{1, 2, 3, 4, 5}}},
+		{"count", 2, 0, [][]int64{{1, 2}, {3, 4}, {5}}},
+		{"payload", 0, 100, [][]int64{{1, 2}, {3}, {4}, {5}}},
+		{"both", 1, 100, [][]int64{{1}, {2}, {3}, {4}, {5}}},
+	}
+	for _, tc := range cases {
+		got := batchPathIDs(planReadBatches(items, tc.maxCount, tc.maxPayload))
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: batches = %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if len(got[i]) != len(tc.want[i]) {
+				t.Errorf("%s: batches = %v, want %v", tc.name, got, tc.want)
+				break
+			}
+			for j := range got[i] {
+				if got[i][j] != tc.want[i][j] {
+					t.Errorf("%s: batches = %v, want %v", tc.name, got, tc.want)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestReadCapacity(t *testing.T) {
+	cases := []struct {
+		asset AssetInfo
+		kind  string
+		want  int64
+	}{
+		{AssetInfo{Size: 10, ImagePayloadCapacity: 500, EstimatedPayloadCapacity: 70}, "image", 500},
+		{AssetInfo{Size: 10, ImagePayloadCapacity: 500, EstimatedPayloadCapacity: 70}, "text_bytes", 70},
+		{AssetInfo{Size: 10}, "image", 160 + imageCapacitySlack},
+		{AssetInfo{Size: 10}, "audio", 20 + otherCapacitySlack},
+		{AssetInfo{Size: -1}, "audio", otherCapacitySlack},
+	}
+	for _, tc := range cases {
+		if got := readCapacity(tc.asset, tc.kind); got != tc.want {
+			t.Errorf("readCapacity(%+v, %q) = %d, want %d", tc.asset, tc.kind, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package assetstudio
+
+import "sync"
+
+const (
+	initialItemStringBytes = 256
+	initialPayloadBytes    = 4 << 20
+)
+
+// BufferPool hands out caller-owned items/payload buffers for direct reads.
+// Buffers grow to the largest size the native side has asked for and are
+// reused across batches, so steady-state reads skip the native allocation and
+// the matching result_free.
+type BufferPool struct {
+	pool sync.Pool
+}
+
+type readBuffers struct {
+	items   []byte
+	payload []byte
+}
+
+func NewBufferPool() *BufferPool {
+	return &BufferPool{pool: sync.Pool{New: func() any { return &readBuffers{} }}}
+}
+
+// get returns buffers sized for count items and payloadHint payload bytes,
+// falling back to initialPayloadBytes when there is no hint.
+func (p *BufferPool) get(count int, itemSize int, payloadHint int64) *readBuffers {
+	b := p.pool.Get().(*readBuffers)
+	if payloadHint <= 0 {
+		payloadHint = initialPayloadBytes
+	}
+	b.reserve(int64(count)*int64(itemSize+initialItemStringBytes), payloadHint)
+	return b
+}
+
+func (p *BufferPool) put(b *readBuffers) {
+	p.pool.Put(b)
+}
+
+// reserve makes sure both buffers are at least the requested sizes. Existing
+// contents are not preserved.
+func (b *readBuffers) reserve(items, payload int64) {
+	if int64(len(b.items)) < items {
+		b.items = make([]byte, items)
+	}
+	if int64(len(b.payload)) < payload {
+		b.payload = make([]byte, payload)
+	}
+}
+
+// grow applies the required_* sizes from a retry response. It reports whether
+// either buffer was too small, i.e. whether a retry can succeed.
+func (b *readBuffers) grow(itemTableBytes, requiredItems, requiredStrings, requiredPayload int64) bool {
+	items := requiredItems
+	if items < itemTableBytes+requiredStrings {
+		items = itemTableBytes + requiredStrings
+	}
+	tooSmall := int64(len(b.items)) < items || int64(len(b.payload)) < requiredPayload
+	b.reserve(items, requiredPayload)
+	return tooSmall
+}
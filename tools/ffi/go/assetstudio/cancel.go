@@ -0,0 +1,62 @@
+package assetstudio
+
+import (
+	"context"
+	"errors"
+	"runtime"
+)
+
+// ErrQuarantined is returned by every call made while a cancelled native call
+// is still running on its abandoned thread.
+var ErrQuarantined = errors.New("assetstudio library is quarantined until a cancelled native call returns")
+
+// runGuarded runs op under ctx. With a cancellable ctx, op runs on its own
+// goroutine locked to a dedicated OS thread; if ctx ends first, runGuarded
+// returns ctx.Err() right away and quarantines the Library. op keeps running
+// and owns every buffer it allocated. Once it returns, the AssetStudio context
+// reported by owned (if any) is closed and the quarantine is lifted.
+func (l *Library) runGuarded(ctx context.Context, owned func() int64, op func() error) error {
+	if l.Quarantined() {
+		return ErrQuarantined
+	}
+	if ctx.Done() == nil {
+		return op()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		// No UnlockOSThread: the thread exits with this goroutine instead of
+		// going back to the scheduler after hosting a possibly wedged call.
+		runtime.LockOSThread()
+		done <- op()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	l.gate.mu.Lock()
+	l.gate.quarantined++
+	l.gate.mu.Unlock()
+	go func() {
+		<-done
+		if id := owned(); id != 0 {
+			_ = l.closeNative(id)
+		}
+		l.gate.mu.Lock()
+		l.gate.quarantined--
+		l.gate.mu.Unlock()
+	}()
+	return ctx.Err()
+}
+
+// Quarantined reports whether a cancelled native call is still running.
+func (l *Library) Quarantined() bool {
+	l.gate.mu.Lock()
+	defer l.gate.mu.Unlock()
+	return l.gate.quarantined > 0
+}
+
+func noContext() int64 { return 0 }
@@ -0,0 +1,129 @@
+package assetstudio
+
+// Capabilities mirrors haruki_assetstudio_capabilities_response without the
+// status fields.
+type Capabilities struct {
+	StructSize                                int  `json:"struct_size"`
+	ABIVersion                                int  `json:"abi_version"`
+	SchemaVersion                             int  `json:"schema_version"`
+	CoreAPIVersionMajor                       int  `json:"core_api_version_major"`
+	CoreAPIVersionMinor                       int  `json:"core_api_version_minor"`
+	ContextABIVersion                         int  `json:"context_abi_version"`
+	ObjectTableABIVersion                     int  `json:"object_table_abi_version"`
+	ObjectTableIntoABIVersion                 int  `json:"object_table_into_abi_version"`
+	ObjectLookupABIVersion                    int  `json:"object_lookup_abi_version"`
+	ObjectLookupIntoABIVersion                int  `json:"object_lookup_into_abi_version"`
+	ObjectReadABIVersion                      int  `json:"object_read_abi_version"`
+	ObjectReadBatchABIVersion                 int  `json:"object_read_batch_abi_version"`
+	ObjectReadBatchHandleABIVersion           int  `json:"object_read_batch_handle_abi_version"`
+	ObjectReadBatchIntoABIVersion             int  `json:"object_read_batch_into_abi_version"`
+	ObjectReadBatchByIndexABIVersion          int  `json:"object_read_batch_by_index_abi_version"`
+	ObjectReadBatchDirectIntoABIVersion       int  `json:"object_read_batch_direct_into_abi_version"`
+	ObjectReadBatchDirectRetryABIVersion      int  `json:"object_read_batch_direct_retry_abi_version"`
+	SupportsTypedObjectTable                  bool `json:"supports_typed_object_table"`
+	SupportsCallerProvidedObjectTableBuffers  bool `json:"supports_caller_provided_object_table_buffers"`
+	SupportsTypedObjectLookup                 bool `json:"supports_typed_object_lookup"`
+	SupportsCallerProvidedObjectLookupBuffers bool `json:"supports_caller_provided_object_lookup_buffers"`
+	SupportsTypedObjectRead                   bool `json:"supports_typed_object_read"`
+	SupportsTypedObjectReadBatch              bool `json:"supports_typed_object_read_batch"`
+	SupportsResultHandle                      bool `json:"supports_result_handle"`
+	SupportsDirectObjectReadRetry             bool `json:"supports_direct_object_read_retry"`
+	SupportsTypedContext                      bool `json:"supports_typed_context"`
+	SupportsNativeDependencyResolver          bool `json:"supports_native_dependency_resolver"`
+	SupportsABILayout                         bool `json:"supports_abi_layout"`
+	SupportsMultipleContexts                  bool `json:"supports_multiple_contexts"`
+	SupportsConcurrentOperations              bool `json:"supports_concurrent_operations"`
+	SupportsContextLifetimeGuards             bool `json:"supports_context_lifetime_guards"`
+	NativeConsoleCapture                      bool `json:"native_console_capture"`
+	Flags                                     int  `json:"flags"`
+}
+
+// Limits mirrors haruki_assetstudio_limits_response without the status fields.
+type Limits struct {
+	StructSize                           int   `json:"struct_size"`
+	ABIVersion                           int   `json:"abi_version"`
+	SchemaVersion                        int   `json:"schema_version"`
+	LimitsABIVersion                     int   `json:"limits_abi_version"`
+	MaxNativeUTF8Bytes                   int   `json:"max_native_utf8_bytes"`
+	MaxObjectReadBatchCount              int   `json:"max_object_read_batch_count"`
+	MaxObjectTablePageLimit              int   `json:"max_object_table_page_limit"`
+	MaxObjectReadBatchPayloadBytes       int64 `json:"max_object_read_batch_payload_bytes"`
+	MaxCachedObjectReadBatchPayloadBytes int64 `json:"max_cached_object_read_batch_payload_bytes"`
+	MaxActiveContexts                    int   `json:"max_active_contexts"`
+	MaxConcurrentOperations              int   `json:"max_concurrent_operations"`
+	SupportsMultipleContexts             bool  `json:"supports_multiple_contexts"`
+	SupportsConcurrentOperations         bool  `json:"supports_concurrent_operations"`
+	LegacyStaticEngine                   bool  `json:"legacy_static_engine"`
+	NativeConsoleCapture                 bool  `json:"native_console_capture"`
+	Flags                                int   `json:"flags"`
+}
+
+// Capabilities asks the loaded library which ABI versions and features it
+// supports.
+func (l *Library) Capabilities() (Capabilities, error) {
+	r, err := l.rawCapabilities()
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return Capabilities{
+		StructSize:                                int(r.Struct_size),
+		ABIVersion:                                int(r.Abi_version),
+		SchemaVersion:                             int(r.Schema_version),
+		CoreAPIVersionMajor:                       int(r.Core_api_version_major),
+		CoreAPIVersionMinor:                       int(r.Core_api_version_minor),
+		ContextABIVersion:                         int(r.Context_abi_version),
+		ObjectTableABIVersion:                     int(r.Object_table_abi_version),
+		ObjectTableIntoABIVersion:                 int(r.Object_table_into_abi_version),
+		ObjectLookupABIVersion:                    int(r.Object_lookup_abi_version),
+		ObjectLookupIntoABIVersion:                int(r.Object_lookup_into_abi_version),
+		ObjectReadABIVersion:                      int(r.Object_read_abi_version),
+		ObjectReadBatchABIVersion:                 int(r.Object_read_batch_abi_version),
+		ObjectReadBatchHandleABIVersion:           int(r.Object_read_batch_handle_abi_version),
+		ObjectReadBatchIntoABIVersion:             int(r.Object_read_batch_into_abi_version),
+		ObjectReadBatchByIndexABIVersion:          int(r.Object_read_batch_by_index_abi_version),
+		ObjectReadBatchDirectIntoABIVersion:       int(r.Object_read_batch_direct_into_abi_version),
+		ObjectReadBatchDirectRetryABIVersion:      int(r.Object_read_batch_direct_retry_abi_version),
+		SupportsTypedObjectTable:                  r.Supports_typed_object_table != 0,
+		SupportsCallerProvidedObjectTableBuffers:  r.Supports_caller_provided_object_table_buffers != 0,
+		SupportsTypedObjectLookup:                 r.Supports_typed_object_lookup != 0,
+		SupportsCallerProvidedObjectLookupBuffers: r.Supports_caller_provided_object_lookup_buffers != 0,
+		SupportsTypedObjectRead:                   r.Supports_typed_object_read != 0,
+		SupportsTypedObjectReadBatch:              r.Supports_typed_object_read_batch != 0,
+		SupportsResultHandle:                      r.Supports_result_handle != 0,
+		SupportsDirectObjectReadRetry:             r.Supports_direct_object_read_retry != 0,
+		SupportsTypedContext:                      r.Supports_typed_context != 0,
+		SupportsNativeDependencyResolver:          r.Supports_native_dependency_resolver != 0,
+		SupportsABILayout:                         r.Supports_abi_layout != 0,
+		SupportsMultipleContexts:                  r.Supports_multiple_contexts != 0,
+		SupportsConcurrentOperations:              r.Supports_concurrent_operations != 0,
+		SupportsContextLifetimeGuards:             r.Supports_context_lifetime_guards != 0,
+		NativeConsoleCapture:                      r.Native_console_capture != 0,
+		Flags:                                     int(r.Flags),
+	}, nil
+}
+
+// Limits asks the loaded library for its batch, page and context limits.
+func (l *Library) Limits() (Limits, error) {
+	r, err := l.rawLimits()
+	if err != nil {
+		return Limits{}, err
+	}
+	return Limits{
+		StructSize:                           int(r.Struct_size),
+		ABIVersion:                           int(r.Abi_version),
+		SchemaVersion:                        int(r.Schema_version),
+		LimitsABIVersion:                     int(r.Limits_abi_version),
+		MaxNativeUTF8Bytes:                   int(r.Max_native_utf8_bytes),
+		MaxObjectReadBatchCount:              int(r.Max_object_read_batch_count),
+		MaxObjectTablePageLimit:              int(r.Max_object_table_page_limit),
+		MaxObjectReadBatchPayloadBytes:       r.Max_object_read_batch_payload_bytes,
+		MaxCachedObjectReadBatchPayloadBytes: r.Max_cached_object_read_batch_payload_bytes,
+		MaxActiveContexts:                    int(r.Max_active_contexts),
+		MaxConcurrentOperations:              int(r.Max_concurrent_operations),
+		SupportsMultipleContexts:             r.Supports_multiple_contexts != 0,
+		SupportsConcurrentOperations:         r.Supports_concurrent_operations != 0,
+		LegacyStaticEngine:                   r.Legacy_static_engine != 0,
+		NativeConsoleCapture:                 r.Native_console_capture != 0,
+		Flags:                                int(r.Flags),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package assetstudio
+
+import (
+	"fmt"
+	"sync"
+)
+
+// callGate bounds concurrent native calls and tracks open contexts so one
+// Library can be shared across goroutines. It is sized from the limits
+// response: libraries without supports_concurrent_operations get a single
+// slot, i.e. every native call is serialised, and libraries without
+// supports_multiple_contexts allow one open context at a time. A limit of 0
+// means no limit; slots is nil then.
+type callGate struct {
+	slots       chan struct{}
+	mu          sync.Mutex
+	contexts    map[int64]struct{}
+	opening     int
+	maxContexts int
+	quarantined int
+}
+
+func newCallGate(limits Limits) *callGate {
+	var slots chan struct{}
+	switch {
+	case !limits.SupportsConcurrentOperations:
+		slots = make(chan struct{}, 1)
+	case limits.MaxConcurrentOperations > 0:
+		slots = make(chan struct{}, limits.MaxConcurrentOperations)
+	}
+	maxContexts := 1
+	if limits.SupportsMultipleContexts {
+		maxContexts = limits.MaxActiveContexts
+	}
+	return &callGate{
+		slots:       slots,
+		contexts:    map[int64]struct{}{},
+		maxContexts: maxContexts,
+	}
+}
+
+// enter blocks until a native call slot is free. The returned func releases
+// it. A nil gate (during load) or one without a limit never blocks.
+func (g *callGate) enter() func() {
+	if g == nil || g.slots == nil {
+		return func() {}
+	}
+	g.slots <- struct{}{}
+	return func() { <-g.slots }
+}
+
+// reserveContext claims room for one more context before context_open runs.
+// The returned func settles the reservation with the opened id, or drops it
+// when id is 0.
+func (g *callGate) reserveContext() (func(id int64), error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.maxContexts > 0 && len(g.contexts)+g.opening >= g.maxContexts {
+		return nil, fmt.Errorf("active context limit reached (%d)", g.maxContexts)
+	}
+	g.opening++
+	return func(id int64) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.opening--
+		if id != 0 {
+			g.contexts[id] = struct{}{}
+		}
+	}, nil
+}
+
+func (g *callGate) checkContext(id int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, found := g.contexts[id]; !found {
+		return fmt.Errorf("%w %d", ErrUnknownContext, id)
+	}
+	return nil
+}
+
+func (g *callGate) forgetContext(id int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.contexts, id)
+}
+
+// ActiveContexts returns the ids of contexts opened through this Library and
+// not yet closed.
+func (l *Library) ActiveContexts() []int64 {
+	l.gate.mu.Lock()
+	defer l.gate.mu.Unlock()
+	ids := make([]int64, 0, len(l.gate.contexts))
+	for id := range l.gate.contexts {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,192 @@
+package assetstudio
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// DefaultPageSize is the object table page size List uses.
+const DefaultPageSize = 2048
+
+// Context is an opened AssetStudio context: one loaded bundle or directory
+// whose objects can be listed and read. A Context is safe for concurrent use;
+// Close it when done so the native side can release the loaded assets.
+//
+// Every method has a ...Context variant taking a context.Context. A native
+// call cannot be interrupted: when ctx ends first, the method returns ctx.Err()
+// at once and the Library refuses new work with ErrQuarantined until the call
+// has returned and the affected context has been closed.
+type Context struct {
+	lib *Library
+	id  int64
+}
+
+// ID returns the native context id.
+func (c *Context) ID() int64 {
+	return c.id
+}
+
+// Open opens a context for path. A non-empty types filter makes the native
+// side load and index only objects of those asset types. Open fails without a
+// native call when the library's active context limit is already reached.
+func (l *Library) Open(path, unityVersion string, types []string) (*Context, error) {
+	return l.OpenContext(context.Background(), path, unityVersion, types)
+}
+
+// OpenContext is Open with cancellation. If ctx ends while context_open is
+// still running, the context it eventually opens is closed again.
+func (l *Library) OpenContext(ctx context.Context, path, unityVersion string, types []string) (*Context, error) {
+	var id int64
+	err := l.runGuarded(ctx, func() int64 { return id }, func() error {
+		var err error
+		id, err = l.openNative(path, unityVersion, types)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Context{lib: l, id: id}, nil
+}
+
+// ListPage returns one page of the object table, filtered to types when it is
+// non-empty. The returned offset is nil on the last page.
+func (c *Context) ListPage(offset, limit int, types []string) ([]AssetInfo, *int, error) {
+	return c.ListPageContext(context.Background(), offset, limit, types)
+}
+
+// ListPageContext is ListPage with cancellation. A cancelled call closes the
+// context once the native side returns.
+func (c *Context) ListPageContext(ctx context.Context, offset, limit int, types []string) ([]AssetInfo, *int, error) {
+	var assets []AssetInfo
+	var next *int
+	err := c.lib.runGuarded(ctx, c.ID, func() error {
+		var err error
+		assets, next, err = c.lib.listObjectsNative(c.id, offset, limit, types)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return assets, next, nil
+}
+
+// List returns the whole object table, filtered to types when it is non-empty.
+func (c *Context) List(types []string) ([]AssetInfo, error) {
+	return c.ListContext(context.Background(), types)
+}
+
+// ListContext is List with cancellation, checked before every page.
+func (c *Context) ListContext(ctx context.Context, types []string) ([]AssetInfo, error) {
+	var out []AssetInfo
+	for a, err := range c.ObjectsContext(ctx, types, DefaultPageSize) {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// Objects iterates over the object table, filtered to types when it is
+// non-empty, fetching pageSize entries at a time as the loop needs them.
+// pageSize is capped at the library's max_object_table_page_limit; zero or
+// less means DefaultPageSize. Breaking out of the loop fetches no more pages.
+// A failed page is yielded once as a zero AssetInfo with the error, and ends
+// the iteration.
+func (c *Context) Objects(types []string, pageSize int) iter.Seq2[AssetInfo, error] {
+	return c.ObjectsContext(context.Background(), types, pageSize)
+}
+
+// ObjectsContext is Objects with cancellation, checked before every page.
+func (c *Context) ObjectsContext(ctx context.Context, types []string, pageSize int) iter.Seq2[AssetInfo, error] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if limit := c.lib.readLimits.MaxObjectTablePageLimit; limit > 0 && pageSize > limit {
+		pageSize = limit
+	}
+	return func(yield func(AssetInfo, error) bool) {
+		offset := 0
+		for {
+			page, next, err := c.ListPageContext(ctx, offset, pageSize, types)
+			if err != nil {
+				yield(AssetInfo{}, err)
+				return
+			}
+			for _, a := range page {
+				if !yield(a, nil) {
+					return
+				}
+			}
+			if next == nil {
+				return
+			}
+			offset = *next
+		}
+	}
+}
+
+// Read reads objects with an explicit kind and image format per item. Items
+// are split into sub-batches that fit max_object_read_batch_count and, using
+// each item's PayloadCapacity, max_object_read_batch_payload_bytes; the
+// results come back merged in item order. Items that fail individually come
+// back with a non-zero Status; the results are then returned together with an
+// error matching ErrPartialFailure.
+func (c *Context) Read(items []ReadItem) ([]ReadResult, error) {
+	return c.ReadContext(context.Background(), items)
+}
+
+// ReadContext is Read with cancellation, checked before every sub-batch. A
+// cancelled batch closes the context once the native side returns.
+func (c *Context) ReadContext(ctx context.Context, items []ReadItem) ([]ReadResult, error) {
+	l := c.lib
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if err := l.gate.checkContext(c.id); err != nil {
+		return nil, err
+	}
+	out := make([]ReadResult, 0, len(items))
+	var partial error
+	for _, batch := range planReadBatches(items, l.readLimits.MaxObjectReadBatchCount, l.readLimits.MaxObjectReadBatchPayloadBytes) {
+		var reads []ReadResult
+		err := l.runGuarded(ctx, c.ID, func() error {
+			var err error
+			reads, err = l.readObjectsBatch(c.id, batch)
+			return err
+		})
+		// On a ctx error the abandoned op may still be writing reads, so it
+		// is only touched once runGuarded has seen op return.
+		if err != nil && !errors.Is(err, ErrPartialFailure) {
+			return out, err
+		}
+		out = append(out, reads...)
+		if err != nil && partial == nil {
+			partial = err
+		}
+	}
+	return out, partial
+}
+
+// ReadImages reads every Texture2D in assets as raw_rgba images.
+func (c *Context) ReadImages(assets []AssetInfo) ([]ReadResult, error) {
+	return c.ReadImagesContext(context.Background(), assets)
+}
+
+// ReadImagesContext is ReadImages with cancellation.
+func (c *Context) ReadImagesContext(ctx context.Context, assets []AssetInfo) ([]ReadResult, error) {
+	return c.ReadContext(ctx, imageReadItems(assets))
+}
+
+// Close closes the context.
+func (c *Context) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// CloseContext is Close with cancellation.
+func (c *Context) CloseContext(ctx context.Context) error {
+	return c.lib.runGuarded(ctx, noContext, func() error {
+		return c.lib.closeNative(c.id)
+	})
+}
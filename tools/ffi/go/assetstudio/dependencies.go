@@ -0,0 +1,85 @@
+package assetstudio
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// LoadOptions configures LoadWithOptions.
+type LoadOptions struct {
+	// Dependencies are native libraries to dlopen with RTLD_GLOBAL before
+	// the main library is opened, so its own lookups by name find them. A
+	// bare file name is looked up next to the main library; a path is used as
+	// is. Every entry must load, or LoadWithOptions fails with
+	// ErrMissingDependency.
+	//
+	// Nil means the DefaultDependencies that are next to the library, like
+	// the Rust client. One of those that fails to load is an error unless the
+	// library advertises supports_native_dependency_resolver and loads its
+	// dependencies itself. An empty non-nil slice preloads nothing.
+	Dependencies []string
+}
+
+// DefaultDependencies lists the AssetStudio native libraries the Rust client
+// preloads on this platform: the texture decoder and the FBX exporter.
+func DefaultDependencies() []string {
+	switch runtime.GOOS {
+	case "linux":
+		return []string{"libTexture2DDecoderNative.so", "libAssetStudioFBXNative.so"}
+	case "darwin":
+		return []string{"libTexture2DDecoderNative.dylib", "libAssetStudioFBXNative.dylib"}
+	case "windows":
+		return []string{"Texture2DDecoderNative.dll", "AssetStudioFBXNative.dll"}
+	default:
+		return nil
+	}
+}
+
+// preloadDependencies loads deps, or the DefaultDependencies next to the main
+// library at path when deps is nil. It runs before the main library is opened,
+// like the Rust client, so the main library's load-time lookups find them.
+// Missing defaults are skipped; every other failure is reported, joined.
+func preloadDependencies(path string, deps []string) error {
+	required := deps != nil
+	if !required {
+		deps = DefaultDependencies()
+	}
+	var errs []error
+	for _, dep := range deps {
+		if filepath.Base(dep) == dep {
+			dep = filepath.Join(filepath.Dir(path), dep)
+		}
+		if _, err := os.Stat(dep); err != nil {
+			if required || !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("%w: %w", ErrMissingDependency, err))
+			}
+			continue
+		}
+		if err := preloadLibrary(dep); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrMissingDependency, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dependencyError returns err, the result of preloadDependencies(path, deps),
+// as the error Load fails with. A failed default only counts when the library
+// does not advertise supports_native_dependency_resolver, which takes the
+// opened library to find out.
+func (l *Library) dependencyError(deps []string, err error) error {
+	if err == nil || deps != nil {
+		return err
+	}
+	caps, capsErr := l.rawCapabilities()
+	if capsErr != nil {
+		return capsErr
+	}
+	if caps.Supports_native_dependency_resolver != 0 {
+		return nil
+	}
+	return err
+}
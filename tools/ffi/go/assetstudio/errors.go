@@ -0,0 +1,77 @@
+package assetstudio
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors for the failures callers handle differently. Match them with
+// errors.Is; the message of a wrapping error carries the details.
+var (
+	// ErrVersionMismatch: the library reports an ABI or schema version this
+	// client does not speak. Use a matching AssetStudioFFI build.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrLayoutMismatch: a struct size reported by abi_layout_v1 differs from
+	// the Go mirror. Use a matching AssetStudioFFI build.
+	ErrLayoutMismatch = errors.New("layout mismatch")
+	// ErrPartialFailure: a read batch finished but some items failed. The
+	// results are still returned; see ReadResult.Err for each item.
+	ErrPartialFailure = errors.New("partial failure")
+	// ErrUnknownContext: the context id was never opened through this Library
+	// or is already closed.
+	ErrUnknownContext = errors.New("unknown context")
+	// ErrBufferTooSmall: caller-provided read buffers were still too small
+	// after growing to the required_* sizes the native side reported.
+	ErrBufferTooSmall = errors.New("buffer too small")
+	// ErrMissingDependency: a native dependency such as the texture decoder
+	// was not found next to the library or failed to load. The message has
+	// the path and the dlerror text.
+	ErrMissingDependency = errors.New("missing native dependency")
+)
+
+// FFIError is a failed native call. Status is the function's return value,
+// ResponseStatus and ErrorCode come from the response struct, and Message is
+// the native error message (error_message_offset) when the call has one.
+// Kind, when set, is one of the sentinel errors above and is what errors.Is
+// matches.
+type FFIError struct {
+	Op             string
+	Status         int
+	ResponseStatus int
+	ErrorCode      int
+	Message        string
+	Kind           error
+}
+
+func (e *FFIError) Error() string {
+	msg := fmt.Sprintf("%s failed status=%d response_status=%d error_code=%d", e.Op, e.Status, e.ResponseStatus, e.ErrorCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *FFIError) Unwrap() error {
+	return e.Kind
+}
+
+// callError builds the error for a failed native call. message is the native
+// error message, or "" when the response carries none. The partial-failure
+// status is the only native value with a sentinel; the other kinds are set
+// by the caller's own checks.
+func callError(op string, status, responseStatus, errorCode int32, message string) *FFIError {
+	err := &FFIError{Op: op, Status: int(status), ResponseStatus: int(responseStatus), ErrorCode: int(errorCode), Message: message}
+	if status == partialFailure || responseStatus == partialFailure {
+		err.Kind = ErrPartialFailure
+	}
+	return err
+}
+
+// Err returns nil for a successful read, or an *FFIError carrying the item's
+// status, error code and native error message.
+func (r ReadResult) Err() error {
+	if r.Status == ok {
+		return nil
+	}
+	return &FFIError{Op: fmt.Sprintf("read path_id=%d", r.PathID), Status: r.Status, ResponseStatus: r.Status, ErrorCode: r.ErrorCode, Message: r.Error}
+}
@@ -0,0 +1,36 @@
+package assetstudio
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFFIErrorMatchesKind(t *testing.T) {
+	err := callError("read", partialFailure, partialFailure, 10, "decode failed")
+	wrapped := fmt.Errorf("bundle a: %w", err)
+	if !errors.Is(wrapped, ErrPartialFailure) || errors.Is(wrapped, ErrBufferTooSmall) {
+		t.Fatalf("errors.Is mismatch for %v", wrapped)
+	}
+	var ffi *FFIError
+	if !errors.As(wrapped, &ffi) || ffi.ErrorCode != 10 {
+		t.Fatalf("errors.As = %+v", ffi)
+	}
+	if want := "read failed status=9 response_status=9 error_code=10: decode failed"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if errors.Is(callError("close", 1, 1, 1, ""), ErrUnknownContext) {
+		t.Error("unclassified error matched a sentinel")
+	}
+}
+
+func TestReadResultErr(t *testing.T) {
+	if err := (ReadResult{PathID: 1}).Err(); err != nil {
+		t.Fatalf("ok read: %v", err)
+	}
+	err := ReadResult{PathID: 7, Status: 10, ErrorCode: 10, Error: "no decoder"}.Err()
+	var ffi *FFIError
+	if !errors.As(err, &ffi) || ffi.Message != "no decoder" || ffi.Status != 10 {
+		t.Fatalf("Err() = %#v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package assetstudio_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"haruki-assetstudio-go-ffi/assetstudio"
+	"haruki-assetstudio-go-ffi/rgbair"
+)
+
+// Open one bundle, list its textures and decode them to PNG.
+func Example() {
+	lib, err := assetstudio.Load(os.Getenv("HARUKI_ASSET_STUDIO_FFI_LIBRARY_PATH"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	types := []string{"Texture2D"}
+	c, err := lib.Open(os.Getenv("HARUKI_SAMPLE_BUNDLE"), "", types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	assets, err := c.List(types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reads, err := c.ReadImages(assets)
+	if err != nil && !errors.Is(err, assetstudio.ErrPartialFailure) {
+		log.Fatal(err)
+	}
+	for _, r := range reads {
+		if err := r.Err(); err != nil {
+			log.Printf("skipping: %v", err)
+			continue
+		}
+		png, err := rgbair.DecodeEncode(r.Payload, rgbair.PNG)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("path_id=%d png=%d bytes\n", r.PathID, len(png))
+	}
+}
+
+// Read every object with the kind the Rust export pipeline would use, giving
+// up on the bundle after a minute.
+func ExampleContext_ReadContext() {
+	lib, err := assetstudio.Load(os.Getenv("HARUKI_ASSET_STUDIO_FFI_LIBRARY_PATH"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c, err := lib.OpenContext(ctx, os.Getenv("HARUKI_SAMPLE_BUNDLE"), "", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	assets, err := c.ListContext(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reads, err := c.ReadContext(ctx, assetstudio.DefaultReadItems(assets, assetstudio.DefaultImageFormat))
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("bundle timed out; library quarantined=%v", lib.Quarantined())
+	case err != nil && !errors.Is(err, assetstudio.ErrPartialFailure):
+		log.Fatal(err)
+	}
+	fmt.Println(len(reads), "objects read")
+}
+
+// Find one object by container without holding the whole object table.
+func ExampleContext_Objects() {
+	lib, err := assetstudio.Load(os.Getenv("HARUKI_ASSET_STUDIO_FFI_LIBRARY_PATH"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, err := lib.Open(os.Getenv("HARUKI_SAMPLE_BUNDLE"), "", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	for a, err := range c.Objects(nil, 512) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		if a.Container == "assets/sekai/assetbundle/resources/startapp/music/jacket/jacket_s_001.png" {
+			fmt.Println("found path_id", a.PathID)
+			break
+		}
+	}
+}
+
+// Reuse Go buffers across read batches instead of native allocations.
+func ExampleLibrary_SetBufferPool() {
+	lib, err := assetstudio.Load(os.Getenv("HARUKI_ASSET_STUDIO_FFI_LIBRARY_PATH"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	lib.SetBufferPool(assetstudio.NewBufferPool())
+}
+
+func ExampleDefaultReadKind() {
+	for _, t := range []string{"Texture2D", "TextAsset", "MonoBehaviour", "Mesh"} {
+		fmt.Println(t, assetstudio.DefaultReadKind(t))
+	}
+	// Output:
+	// Texture2D image
+	// TextAsset text_bytes
+	// MonoBehaviour typetree_json
+	// Mesh obj
+}
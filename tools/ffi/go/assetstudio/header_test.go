@@ -0,0 +1,154 @@
+package assetstudio
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// cStructNames maps every #[repr(C)] struct in native.rs to its typedef in the
+// vendored header.
+var cStructNames = map[string]string{
+	"AssetStudioTypedCapabilitiesResponse":         "haruki_assetstudio_capabilities_response",
+	"AssetStudioTypedAbiLayoutResponse":            "haruki_assetstudio_abi_layout_response",
+	"AssetStudioTypedLimitsResponse":               "haruki_assetstudio_limits_response",
+	"AssetStudioTypedContextOpenRequest":           "haruki_assetstudio_context_open_request",
+	"AssetStudioTypedContextOpenResponse":          "haruki_assetstudio_context_open_response",
+	"AssetStudioTypedContextCloseRequest":          "haruki_assetstudio_context_close_request",
+	"AssetStudioTypedContextCloseResponse":         "haruki_assetstudio_context_close_response",
+	"AssetStudioTypedObjectListRequest":            "haruki_assetstudio_object_list_request",
+	"AssetStudioTypedObjectListIntoRequest":        "haruki_assetstudio_object_list_into_request_v1",
+	"AssetStudioTypedAssetObject":                  "haruki_assetstudio_asset_object",
+	"AssetStudioTypedObjectTable":                  "haruki_assetstudio_object_table",
+	"AssetStudioTypedObjectReadItemRequest":        "haruki_assetstudio_object_read_item_request",
+	"AssetStudioTypedObjectReadBatchIntoRequest":   "haruki_assetstudio_object_read_batch_into_request_v1",
+	"AssetStudioTypedObjectReadBatchRetryResponse": "haruki_assetstudio_object_read_batch_retry_response_v1",
+	"AssetStudioTypedObjectReadItemResponse":       "haruki_assetstudio_object_read_item_response_v1",
+}
+
+var rustScalarTypes = map[string]string{
+	"c_int":      "int32_t",
+	"c_longlong": "int64_t",
+	"c_uchar":    "uint8_t",
+	"c_char":     "char",
+}
+
+type structField struct {
+	name  string
+	cType string
+}
+
+var (
+	rustStructPattern = regexp.MustCompile(`(?s)#\[repr\(C\)\]\s*(?:#\[[^\]]*\]\s*)*(?:pub(?:\([a-z]+\))?\s+)?struct\s+(\w+)\s*\{(.*?)\n\}`)
+	rustFieldPattern  = regexp.MustCompile(`^\s*(?:pub(?:\([a-z]+\))?\s+)?(\w+)\s*:\s*(.+?),?\s*$`)
+	cStructPattern    = regexp.MustCompile(`(?s)typedef struct (\w+) \{(.*?)\n\} (\w+);`)
+	cFieldPattern     = regexp.MustCompile(`^\s*(.+?)\s*(\w+);\s*$`)
+)
+
+// rustFieldType renders a Rust FFI field type the way the header spells it.
+func rustFieldType(t *testing.T, rustType string) string {
+	rustType = strings.TrimSpace(rustType)
+	prefix := ""
+	pointer := ""
+	switch {
+	case strings.HasPrefix(rustType, "*const "):
+		prefix, pointer = "const ", "*"
+		rustType = strings.TrimPrefix(rustType, "*const ")
+	case strings.HasPrefix(rustType, "*mut "):
+		pointer = "*"
+		rustType = strings.TrimPrefix(rustType, "*mut ")
+	}
+	if scalar, found := rustScalarTypes[rustType]; found {
+		return prefix + scalar + pointer
+	}
+	if name, found := cStructNames[rustType]; found {
+		return prefix + name + pointer
+	}
+	t.Fatalf("native.rs uses FFI type %q that the drift check does not know", rustType)
+	return ""
+}
+
+func parseRustStructs(t *testing.T, source string) map[string][]structField {
+	structs := map[string][]structField{}
+	for _, m := range rustStructPattern.FindAllStringSubmatch(source, -1) {
+		cName, found := cStructNames[m[1]]
+		if !found {
+			t.Fatalf("native.rs has #[repr(C)] struct %s with no vendored header counterpart", m[1])
+		}
+		var fields []structField
+		for _, line := range strings.Split(m[2], "\n") {
+			if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "//") {
+				continue
+			}
+			f := rustFieldPattern.FindStringSubmatch(line)
+			if f == nil {
+				t.Fatalf("cannot parse field %q of %s", line, m[1])
+			}
+			fields = append(fields, structField{name: f[1], cType: rustFieldType(t, f[2])})
+		}
+		structs[cName] = fields
+	}
+	return structs
+}
+
+func parseHeaderStructs(t *testing.T, header string) map[string][]structField {
+	structs := map[string][]structField{}
+	for _, m := range cStructPattern.FindAllStringSubmatch(header, -1) {
+		if m[1] != m[3] {
+			t.Fatalf("header typedef %s names struct %s", m[3], m[1])
+		}
+		var fields []structField
+		for _, line := range strings.Split(m[2], "\n") {
+			if line = strings.TrimSpace(line); line == "" {
+				continue
+			}
+			f := cFieldPattern.FindStringSubmatch(line)
+			if f == nil {
+				t.Fatalf("cannot parse field %q of %s", line, m[1])
+			}
+			fields = append(fields, structField{name: f[2], cType: strings.ReplaceAll(f[1], " *", "*")})
+		}
+		structs[m[1]] = fields
+	}
+	return structs
+}
+
+func TestVendoredHeaderMatchesRustDefinitions(t *testing.T) {
+	rustSource, err := os.ReadFile(filepath.Join("..", "..", "..", "..", "crates", "assetstudio-ffi", "src", "native.rs"))
+	if err != nil {
+		t.Skipf("Rust sources not available: %v", err)
+	}
+	header, err := os.ReadFile(filepath.Join("include", "haruki_assetstudio_native.h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rust := parseRustStructs(t, string(rustSource))
+	c := parseHeaderStructs(t, string(header))
+	for name := range cStructNames {
+		if _, found := rust[cStructNames[name]]; !found {
+			t.Errorf("native.rs no longer defines #[repr(C)] struct %s", name)
+		}
+	}
+	for name, rustFields := range rust {
+		cFields, found := c[name]
+		if !found {
+			t.Errorf("vendored header is missing %s", name)
+			continue
+		}
+		if len(cFields) != len(rustFields) {
+			t.Errorf("%s: header has %d fields, native.rs has %d", name, len(cFields), len(rustFields))
+		}
+		for i := range min(len(cFields), len(rustFields)) {
+			if cFields[i] != rustFields[i] {
+				t.Errorf("%s field %d: header %s %s, native.rs %s %s", name, i, cFields[i].cType, cFields[i].name, rustFields[i].cType, rustFields[i].name)
+			}
+		}
+	}
+	for name := range c {
+		if _, found := rust[name]; !found {
+			t.Errorf("vendored header defines %s which native.rs does not", name)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * Typed C ABI exported by HarukiAssetStudioFFI (NativeAOT).
+ *
+ * Vendored copy for the Go direct client. Struct layouts must stay in sync with
+ * the #[repr(C)] definitions in crates/assetstudio-ffi/src/native.rs; the Go
+ * test TestVendoredHeaderMatchesRustDefinitions fails when they drift apart.
+ */
+#ifndef HARUKI_ASSETSTUDIO_NATIVE_H
+#define HARUKI_ASSETSTUDIO_NATIVE_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct haruki_assetstudio_capabilities_response {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t status;
+    int32_t error_code;
+    int32_t core_api_version_major;
+    int32_t core_api_version_minor;
+    int32_t context_abi_version;
+    int32_t object_table_abi_version;
+    int32_t object_table_into_abi_version;
+    int32_t object_lookup_abi_version;
+    int32_t object_lookup_into_abi_version;
+    int32_t object_read_abi_version;
+    int32_t object_read_batch_abi_version;
+    int32_t object_read_batch_handle_abi_version;
+    int32_t object_read_batch_into_abi_version;
+    int32_t object_read_batch_by_index_abi_version;
+    int32_t object_read_batch_direct_into_abi_version;
+    int32_t object_read_batch_direct_retry_abi_version;
+    int32_t supports_typed_object_table;
+    int32_t supports_caller_provided_object_table_buffers;
+    int32_t supports_typed_object_lookup;
+    int32_t supports_caller_provided_object_lookup_buffers;
+    int32_t supports_typed_object_read;
+    int32_t supports_typed_object_read_batch;
+    int32_t supports_result_handle;
+    int32_t supports_direct_object_read_retry;
+    int32_t supports_typed_context;
+    int32_t supports_native_dependency_resolver;
+    int32_t supports_abi_layout;
+    int32_t supports_multiple_contexts;
+    int32_t supports_concurrent_operations;
+    int32_t supports_context_lifetime_guards;
+    int32_t native_console_capture;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_capabilities_response;
+
+typedef struct haruki_assetstudio_abi_layout_response {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t status;
+    int32_t error_code;
+    int32_t layout_version;
+    int32_t context_open_request;
+    int32_t context_open_response;
+    int32_t context_close_request;
+    int32_t context_close_response;
+    int32_t limits_response;
+    int32_t capabilities_response;
+    int32_t object_list_request;
+    int32_t object_list_into_request_v1;
+    int32_t object_table;
+    int32_t asset_object;
+    int32_t object_read_item_request;
+    int32_t object_read_batch_into_request_v1;
+    int32_t object_read_item_response_v1;
+    int32_t object_read_batch_retry_response_v1;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_abi_layout_response;
+
+typedef struct haruki_assetstudio_limits_response {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t limits_abi_version;
+    int32_t status;
+    int32_t error_code;
+    int32_t max_native_utf8_bytes;
+    int32_t max_object_read_batch_count;
+    int32_t max_object_table_page_limit;
+    int64_t max_object_read_batch_payload_bytes;
+    int64_t max_cached_object_read_batch_payload_bytes;
+    int32_t max_active_contexts;
+    int32_t max_concurrent_operations;
+    int32_t supports_multiple_contexts;
+    int32_t supports_concurrent_operations;
+    int32_t legacy_static_engine;
+    int32_t native_console_capture;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_limits_response;
+
+typedef struct haruki_assetstudio_context_open_request {
+    int32_t struct_size;
+    const uint8_t* input_path_utf8;
+    int32_t input_path_utf8_len;
+    const uint8_t* unity_version_utf8;
+    int32_t unity_version_utf8_len;
+    const uint8_t* asset_types_csv_utf8;
+    int32_t asset_types_csv_utf8_len;
+    const uint8_t* output_dir_utf8;
+    int32_t output_dir_utf8_len;
+    int32_t load_all_assets;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_context_open_request;
+
+typedef struct haruki_assetstudio_context_open_response {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t context_abi_version;
+    int32_t status;
+    int32_t error_code;
+    int64_t context_id;
+    int32_t assets_file_count;
+    int32_t exportable_asset_count;
+    int32_t object_index_count;
+    int32_t has_more_assets;
+    uint8_t* unity_version_utf8;
+    int32_t unity_version_utf8_len;
+    uint8_t* buffer;
+    int64_t buffer_len;
+    int64_t duration_ms;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_context_open_response;
+
+typedef struct haruki_assetstudio_context_close_request {
+    int32_t struct_size;
+    int64_t context_id;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_context_close_request;
+
+typedef struct haruki_assetstudio_context_close_response {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t context_abi_version;
+    int32_t status;
+    int32_t error_code;
+    int64_t context_id;
+    int64_t duration_ms;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_context_close_response;
+
+typedef struct haruki_assetstudio_object_list_request {
+    int32_t struct_size;
+    int64_t context_id;
+    int32_t offset;
+    int32_t limit;
+    const uint8_t* asset_types_csv_utf8;
+    int32_t asset_types_csv_utf8_len;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_object_list_request;
+
+typedef struct haruki_assetstudio_object_list_into_request_v1 {
+    int32_t struct_size;
+    int64_t context_id;
+    int32_t offset;
+    int32_t limit;
+    const uint8_t* asset_types_csv_utf8;
+    int32_t asset_types_csv_utf8_len;
+    int32_t flags;
+    int32_t reserved;
+    uint8_t* buffer;
+    int64_t buffer_len;
+} haruki_assetstudio_object_list_into_request_v1;
+
+typedef struct haruki_assetstudio_asset_object {
+    int32_t index;
+    int32_t type_id;
+    int64_t path_id;
+    int64_t size;
+    int64_t estimated_payload_capacity;
+    int64_t raw_payload_capacity;
+    int64_t image_payload_capacity;
+    int64_t text_payload_capacity;
+    int32_t payload_capacity_flags;
+    int32_t reserved;
+    int32_t name_offset;
+    int32_t name_len;
+    int32_t container_offset;
+    int32_t container_len;
+    int32_t type_offset;
+    int32_t type_len;
+    int32_t unique_id_offset;
+    int32_t unique_id_len;
+    int32_t source_file_offset;
+    int32_t source_file_len;
+} haruki_assetstudio_asset_object;
+
+typedef struct haruki_assetstudio_object_table {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t object_table_abi_version;
+    int32_t status;
+    int32_t error_code;
+    int64_t context_id;
+    int32_t offset;
+    int32_t limit;
+    int32_t next_offset;
+    int32_t has_more;
+    int32_t total_count;
+    int32_t returned_count;
+    haruki_assetstudio_asset_object* objects;
+    uint8_t* string_data;
+    int32_t string_data_len;
+    uint8_t* buffer;
+    int64_t buffer_len;
+    int64_t duration_ms;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_object_table;
+
+typedef struct haruki_assetstudio_object_read_item_request {
+    int64_t path_id;
+    const uint8_t* kind_utf8;
+    int32_t kind_utf8_len;
+    const uint8_t* image_format_utf8;
+    int32_t image_format_utf8_len;
+} haruki_assetstudio_object_read_item_request;
+
+typedef struct haruki_assetstudio_object_read_batch_into_request_v1 {
+    int32_t struct_size;
+    int64_t context_id;
+    const haruki_assetstudio_object_read_item_request* items;
+    int32_t count;
+    int32_t flags;
+    uint8_t* items_buffer;
+    int64_t items_buffer_len;
+    uint8_t* payload;
+    int64_t payload_len;
+    int32_t reserved;
+} haruki_assetstudio_object_read_batch_into_request_v1;
+
+typedef struct haruki_assetstudio_object_read_item_response_v1 {
+    int32_t index;
+    int32_t status;
+    int32_t error_code;
+    int64_t path_id;
+    int32_t type_id;
+    int64_t size;
+    int64_t payload_offset;
+    int64_t payload_len;
+    int32_t payload_kind_offset;
+    int32_t payload_kind_len;
+    int32_t suggested_extension_offset;
+    int32_t suggested_extension_len;
+    int32_t error_message_offset;
+    int32_t error_message_len;
+} haruki_assetstudio_object_read_item_response_v1;
+
+typedef struct haruki_assetstudio_object_read_batch_retry_response_v1 {
+    int32_t struct_size;
+    int32_t abi_version;
+    int32_t schema_version;
+    int32_t object_read_batch_abi_version;
+    int32_t object_read_batch_into_abi_version;
+    int32_t object_read_batch_direct_retry_abi_version;
+    int32_t status;
+    int32_t error_code;
+    int64_t context_id;
+    int32_t requested_count;
+    int32_t returned_count;
+    int32_t failed_count;
+    haruki_assetstudio_object_read_item_response_v1* items;
+    uint8_t* string_data;
+    int32_t string_data_len;
+    uint8_t* items_buffer;
+    int64_t items_buffer_len;
+    uint8_t* payload;
+    int64_t payload_len;
+    int64_t required_items_buffer_len;
+    int32_t required_string_data_len;
+    int64_t required_payload_len;
+    int64_t duration_ms;
+    int64_t result_handle;
+    int32_t ownership_flags;
+    int32_t flags;
+    int32_t reserved;
+} haruki_assetstudio_object_read_batch_retry_response_v1;
+
+int32_t haruki_assetstudio_capabilities_v1(haruki_assetstudio_capabilities_response* response);
+int32_t haruki_assetstudio_abi_layout_v1(haruki_assetstudio_abi_layout_response* response);
+int32_t haruki_assetstudio_limits_v1(haruki_assetstudio_limits_response* response);
+int32_t haruki_assetstudio_context_open_v1(const haruki_assetstudio_context_open_request* request, haruki_assetstudio_context_open_response* response);
+int32_t haruki_assetstudio_context_list_objects_size_v1(const haruki_assetstudio_object_list_request* request, haruki_assetstudio_object_table* response);
+int32_t haruki_assetstudio_context_list_objects_into_v1(const haruki_assetstudio_object_list_into_request_v1* request, haruki_assetstudio_object_table* response);
+int32_t haruki_assetstudio_context_read_objects_direct_retry_v1(const haruki_assetstudio_object_read_batch_into_request_v1* request, haruki_assetstudio_object_read_batch_retry_response_v1* response);
+int32_t haruki_assetstudio_context_close_v1(const haruki_assetstudio_context_close_request* request, haruki_assetstudio_context_close_response* response);
+int32_t haruki_assetstudio_result_free(int64_t result_handle);
+void haruki_assetstudio_free_buffer(uint8_t* buffer);
+void haruki_assetstudio_free_string(char* value);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,582 @@
+// Package assetstudio calls HarukiAssetStudioFFI through its typed C ABI in
+// the caller's process.
+//
+// Load the library once with Load, open a Context per bundle with
+// Library.Open, list its objects with Context.List and read the ones you need
+// with Context.Read. Close the Context when done.
+//
+// Load negotiates a version for each sub-ABI the library advertises (see
+// Library.ABIVersions) and checks its struct sizes against the Go mirrors of
+// include/haruki_assetstudio_native.h. The library is loaded with
+// cgo by default, or with purego when cgo is off or the purego build tag is
+// set; see Backend.
+package assetstudio
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"unicode"
+	"unsafe"
+)
+
+const ok = 0
+const partialFailure = 9
+const typedABIVersion = 1
+const typedSchemaVersion = 1
+const typedLayoutVersion = 1
+const typedLimitsABIVersion = 1
+
+// DefaultImageFormat is the image format ReadImages asks for: the native RGBA
+// IR container decoded by the rgbair package.
+const DefaultImageFormat = "raw_rgba"
+
+// Library is a loaded HarukiAssetStudioFFI. It is safe for concurrent use:
+// native calls are gated by the library's concurrency limits (see callGate).
+type Library struct {
+	native     *backend
+	buffers    *BufferPool
+	readLimits Limits
+	abi        ABIVersions
+	strides    elementStrides
+	gate       *callGate
+}
+
+type AssetInfo struct {
+	Index      int    `json:"index"`
+	TypeID     int    `json:"type_id"`
+	PathID     int64  `json:"path_id"`
+	Size       int64  `json:"size"`
+	Name       string `json:"name,omitempty"`
+	Container  string `json:"container,omitempty"`
+	Type       string `json:"type,omitempty"`
+	UniqueID   string `json:"unique_id,omitempty"`
+	SourceFile string `json:"source_file,omitempty"`
+
+	EstimatedPayloadCapacity int64 `json:"estimated_payload_capacity,omitempty"`
+	RawPayloadCapacity       int64 `json:"raw_payload_capacity,omitempty"`
+	ImagePayloadCapacity     int64 `json:"image_payload_capacity,omitempty"`
+	TextPayloadCapacity      int64 `json:"text_payload_capacity,omitempty"`
+}
+
+// ReadItem selects one object to read. PayloadCapacity is the expected payload
+// size used to plan batches; zero means unknown.
+type ReadItem struct {
+	PathID          int64  `json:"path_id"`
+	Kind            string `json:"kind"`
+	ImageFormat     string `json:"image_format"`
+	PayloadCapacity int64  `json:"payload_capacity,omitempty"`
+}
+
+type ReadResult struct {
+	PathID             int64  `json:"path_id"`
+	Status             int    `json:"status"`
+	ErrorCode          int    `json:"error_code"`
+	PayloadKind        string `json:"payload_kind,omitempty"`
+	SuggestedExtension string `json:"suggested_extension,omitempty"`
+	PayloadLen         int    `json:"payload_len"`
+	Payload            []byte `json:"-"`
+	Error              string `json:"error,omitempty"`
+}
+
+// DefaultReadKind maps an asset type to the read kind the Rust export pipeline
+// uses for it (default_native_read_kind in export_pipeline/implementation/assetstudio.rs).
+func DefaultReadKind(assetType string) string {
+	switch normalizeTypeName(assetType) {
+	case "texture2d", "texture2darray", "texture2darrayimage", "sprite":
+		return "image"
+	case "textasset":
+		return "text_bytes"
+	case "monobehaviour", "monobehavior":
+		return "typetree_json"
+	case "audioclip":
+		return "audio"
+	case "videoclip", "movietexture":
+		return "video"
+	case "font":
+		return "font"
+	case "shader", "shadervariantcollection":
+		return "shader"
+	case "mesh":
+		return "obj"
+	case "animator":
+		return "fbx"
+	default:
+		return "typetree_json"
+	}
+}
+
+func normalizeTypeName(value string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteString(strings.ToLower(string(r)))
+	}
+	return b.String()
+}
+
+// DefaultReadItems builds one read item per asset using DefaultReadKind.
+func DefaultReadItems(assets []AssetInfo, imageFormat string) []ReadItem {
+	items := make([]ReadItem, 0, len(assets))
+	for _, a := range assets {
+		if strings.TrimSpace(a.Type) == "" {
+			continue
+		}
+		kind := DefaultReadKind(a.Type)
+		items = append(items, ReadItem{PathID: a.PathID, Kind: kind, ImageFormat: imageFormat, PayloadCapacity: readCapacity(a, kind)})
+	}
+	return items
+}
+
+// utf8Arg returns the pointer/length pair for s in a request struct. The bytes
+// stay pinned until pin is released.
+func utf8Arg(pin *runtime.Pinner, s string) (*byte, int32) {
+	if s == "" {
+		return nil, 0
+	}
+	b := []byte(s)
+	pin.Pin(&b[0])
+	return &b[0], int32(len(b))
+}
+
+// elementStrides are the native sizes of the array elements the library
+// fills, from abi_layout_v1. They can exceed the Go mirrors when a sub-ABI was
+// negotiated down.
+type elementStrides struct {
+	assetObject uintptr
+	readItem    uintptr
+}
+
+// nativeElem returns element i of a native array whose elements are stride
+// bytes apart; the Go mirror T covers a prefix of each.
+func nativeElem[T any](base *T, i int, stride uintptr) *T {
+	return (*T)(unsafe.Add(unsafe.Pointer(base), uintptr(i)*stride))
+}
+
+// nativeBytes views n bytes at base+offset without copying.
+func nativeBytes(base *byte, offset, n int64) []byte {
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(base), offset)), n)
+}
+
+func goString(base *byte, offset int32, length int32) string {
+	if base == nil || offset < 0 || length <= 0 {
+		return ""
+	}
+	return string(nativeBytes(base, int64(offset), int64(length)))
+}
+
+// payloadBytes copies one item's payload out of the native result so it stays
+// valid after haruki_assetstudio_result_free releases the batch.
+func payloadBytes(r *objectReadBatchRetryResponseV1, it *objectReadItemResponseV1) ([]byte, error) {
+	if it.Status != ok || r.Payload == nil || it.Payload_len <= 0 {
+		return nil, nil
+	}
+	if it.Payload_offset < 0 || it.Payload_len > r.Payload_len || it.Payload_offset > r.Payload_len-it.Payload_len {
+		return nil, fmt.Errorf("read payload out of range path_id=%d offset=%d len=%d payload_len=%d", it.Path_id, it.Payload_offset, it.Payload_len, r.Payload_len)
+	}
+	return bytes.Clone(nativeBytes(r.Payload, it.Payload_offset, it.Payload_len)), nil
+}
+
+// Load opens the HarukiAssetStudioFFI library at path with the default
+// LoadOptions. Load fails with ErrVersionMismatch or ErrLayoutMismatch when
+// the library does not speak this package's typed ABI, and with
+// ErrMissingDependency when a native dependency cannot be preloaded. A library
+// with newer sub-ABIs than this package knows is used through the newest
+// versions both sides support.
+func Load(path string) (*Library, error) {
+	return LoadWithOptions(path, LoadOptions{})
+}
+
+// LoadWithOptions is Load with explicit options.
+func LoadWithOptions(path string, opts LoadOptions) (*Library, error) {
+	os.Setenv("HARUKI_ASSET_STUDIO_FFI_LIBRARY_PATH", path)
+	depErr := preloadDependencies(path, opts.Dependencies)
+	if depErr != nil && opts.Dependencies != nil {
+		return nil, depErr
+	}
+	native, err := openBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	lib := &Library{native: native}
+	if err := lib.verifyLayout(); err != nil {
+		return nil, err
+	}
+	if err := lib.dependencyError(opts.Dependencies, depErr); err != nil {
+		return nil, err
+	}
+	limits, err := lib.Limits()
+	if err != nil {
+		return nil, err
+	}
+	lib.readLimits = limits
+	lib.gate = newCallGate(limits)
+	return lib, nil
+}
+
+func (l *Library) rawCapabilities() (capabilitiesResponse, error) {
+	defer l.gate.enter()()
+	var r capabilitiesResponse
+	status := l.native.capabilities(&r)
+	if status != ok || r.Status != ok {
+		return r, callError("capabilities", status, r.Status, r.Error_code, "")
+	}
+	return r, nil
+}
+
+func (l *Library) rawLimits() (limitsResponse, error) {
+	defer l.gate.enter()()
+	var r limitsResponse
+	status := l.native.limits(&r)
+	if status != ok || r.Status != ok {
+		return r, callError("limits", status, r.Status, r.Error_code, "")
+	}
+	return r, nil
+}
+
+func (l *Library) verifyLayout() error {
+	caps, err := l.rawCapabilities()
+	if err != nil {
+		return err
+	}
+	if int(caps.Struct_size) != int(unsafe.Sizeof(capabilitiesResponse{})) {
+		return fmt.Errorf("capabilities %w native=%d go=%d", ErrLayoutMismatch, caps.Struct_size, unsafe.Sizeof(capabilitiesResponse{}))
+	}
+	capabilityVersions := map[string][2]int{
+		"capabilities_v1 abi":    {int(caps.Abi_version), typedABIVersion},
+		"capabilities_v1 schema": {int(caps.Schema_version), typedSchemaVersion},
+	}
+	for name, pair := range capabilityVersions {
+		if pair[0] != pair[1] {
+			return fmt.Errorf("%s %w native=%d go=%d", name, ErrVersionMismatch, pair[0], pair[1])
+		}
+	}
+	versions, downgraded, err := negotiateABI(&caps)
+	if err != nil {
+		return err
+	}
+	var r abiLayoutResponse
+	status := l.native.abiLayout(&r)
+	if status != ok || r.Status != ok {
+		return callError("abi_layout", status, r.Status, r.Error_code, "")
+	}
+	layoutVersions := map[string][2]int{
+		"abi_layout_v1 abi":    {int(r.Abi_version), typedABIVersion},
+		"abi_layout_v1 schema": {int(r.Schema_version), typedSchemaVersion},
+		"abi_layout_v1 layout": {int(r.Layout_version), typedLayoutVersion},
+	}
+	for name, pair := range layoutVersions {
+		if pair[0] != pair[1] {
+			return fmt.Errorf("%s %w native=%d go=%d", name, ErrVersionMismatch, pair[0], pair[1])
+		}
+	}
+	checks := map[string][2]int{
+		"capabilities_response":               {int(r.Capabilities_response), int(unsafe.Sizeof(capabilitiesResponse{}))},
+		"context_open_request":                {int(r.Context_open_request), int(unsafe.Sizeof(contextOpenRequest{}))},
+		"context_open_response":               {int(r.Context_open_response), int(unsafe.Sizeof(contextOpenResponse{}))},
+		"limits_response":                     {int(r.Limits_response), int(unsafe.Sizeof(limitsResponse{}))},
+		"object_table":                        {int(r.Object_table), int(unsafe.Sizeof(objectTable{}))},
+		"asset_object":                        {int(r.Asset_object), int(unsafe.Sizeof(assetObject{}))},
+		"object_read_item_request":            {int(r.Object_read_item_request), int(unsafe.Sizeof(objectReadItemRequest{}))},
+		"object_read_batch_into_request_v1":   {int(r.Object_read_batch_into_request_v1), int(unsafe.Sizeof(objectReadBatchIntoRequestV1{}))},
+		"object_read_item_response_v1":        {int(r.Object_read_item_response_v1), int(unsafe.Sizeof(objectReadItemResponseV1{}))},
+		"object_read_batch_retry_response_v1": {int(r.Object_read_batch_retry_response_v1), int(unsafe.Sizeof(objectReadBatchRetryResponseV1{}))},
+	}
+	for name, pair := range checks {
+		if pair[0] == pair[1] {
+			continue
+		}
+		// A newer sub-ABI may grow the elements of an array the native side
+		// fills: those are walked with the native stride and only their v1
+		// prefix is read. Every other struct must match exactly.
+		if sub, found := arrayElementSubABI[name]; found && downgraded[sub] && pair[0] > pair[1] {
+			continue
+		}
+		return fmt.Errorf("%w %s native=%d go=%d", ErrLayoutMismatch, name, pair[0], pair[1])
+	}
+	limits, err := l.rawLimits()
+	if err != nil {
+		return err
+	}
+	if int(limits.Struct_size) != int(unsafe.Sizeof(limitsResponse{})) {
+		return fmt.Errorf("limits %w native=%d go=%d", ErrLayoutMismatch, limits.Struct_size, unsafe.Sizeof(limitsResponse{}))
+	}
+	limitVersions := map[string][2]int{
+		"limits_v1 abi":    {int(limits.Abi_version), typedABIVersion},
+		"limits_v1 schema": {int(limits.Schema_version), typedSchemaVersion},
+		"limits_v1 limits": {int(limits.Limits_abi_version), typedLimitsABIVersion},
+	}
+	for name, pair := range limitVersions {
+		if pair[0] != pair[1] {
+			return fmt.Errorf("%s %w native=%d go=%d", name, ErrVersionMismatch, pair[0], pair[1])
+		}
+	}
+	l.abi = versions
+	l.strides = elementStrides{assetObject: uintptr(r.Asset_object), readItem: uintptr(r.Object_read_item_response_v1)}
+	return nil
+}
+
+// ABIVersions reports the sub-ABI versions negotiated when the library was
+// loaded.
+func (l *Library) ABIVersions() ABIVersions {
+	return l.abi
+}
+
+// assetTypesCSV joins a type filter the way the Rust adapter does. Blank
+// entries are dropped; an empty result means no filter.
+func assetTypesCSV(types []string) string {
+	kept := make([]string, 0, len(types))
+	for _, t := range types {
+		if t = strings.TrimSpace(t); t != "" {
+			kept = append(kept, t)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+func (l *Library) openNative(path, unityVersion string, types []string) (int64, error) {
+	settle, err := l.gate.reserveContext()
+	if err != nil {
+		return 0, err
+	}
+	var contextID int64
+	defer func() { settle(contextID) }()
+	defer l.gate.enter()()
+	var pin runtime.Pinner
+	defer pin.Unpin()
+	q := contextOpenRequest{Struct_size: int32(unsafe.Sizeof(contextOpenRequest{})), Load_all_assets: 1}
+	q.Input_path_utf8, q.Input_path_utf8_len = utf8Arg(&pin, path)
+	q.Unity_version_utf8, q.Unity_version_utf8_len = utf8Arg(&pin, unityVersion)
+	q.Asset_types_csv_utf8, q.Asset_types_csv_utf8_len = utf8Arg(&pin, assetTypesCSV(types))
+	var r contextOpenResponse
+	status := l.native.contextOpen(&q, &r)
+	if r.Buffer != nil {
+		l.native.freeBuffer(r.Buffer)
+	}
+	if status != ok || r.Status != ok {
+		return 0, callError("context_open", status, r.Status, r.Error_code, "")
+	}
+	contextID = r.Context_id
+	return contextID, nil
+}
+
+func (l *Library) listObjectsNative(contextID int64, offset, limit int, types []string) ([]AssetInfo, *int, error) {
+	if err := l.gate.checkContext(contextID); err != nil {
+		return nil, nil, err
+	}
+	defer l.gate.enter()()
+	var pin runtime.Pinner
+	defer pin.Unpin()
+	q := objectListRequest{Struct_size: int32(unsafe.Sizeof(objectListRequest{})), Context_id: contextID, Offset: int32(offset), Limit: int32(limit)}
+	q.Asset_types_csv_utf8, q.Asset_types_csv_utf8_len = utf8Arg(&pin, assetTypesCSV(types))
+	var size objectTable
+	status := l.native.listSize(&q, &size)
+	if status != ok || size.Status != ok {
+		return nil, nil, callError("list size", status, size.Status, size.Error_code, "")
+	}
+	qi := objectListIntoRequestV1{Struct_size: int32(unsafe.Sizeof(objectListIntoRequestV1{})), Context_id: contextID, Offset: int32(offset), Limit: int32(limit), Asset_types_csv_utf8: q.Asset_types_csv_utf8, Asset_types_csv_utf8_len: q.Asset_types_csv_utf8_len}
+	if size.Buffer_len > 0 {
+		buf := make([]byte, size.Buffer_len)
+		pin.Pin(&buf[0])
+		qi.Buffer, qi.Buffer_len = &buf[0], size.Buffer_len
+	}
+	var r objectTable
+	status = l.native.listInto(&qi, &r)
+	if status != ok || r.Status != ok {
+		return nil, nil, callError("list into", status, r.Status, r.Error_code, "")
+	}
+	assets := make([]AssetInfo, 0, int(r.Returned_count))
+	for i := range int(r.Returned_count) {
+		o := nativeElem(r.Objects, i, l.strides.assetObject)
+		assets = append(assets, AssetInfo{Index: int(o.Index), TypeID: int(o.Type_id), PathID: o.Path_id, Size: o.Size, Name: goString(r.String_data, o.Name_offset, o.Name_len), Container: goString(r.String_data, o.Container_offset, o.Container_len), Type: goString(r.String_data, o.Type_offset, o.Type_len), UniqueID: goString(r.String_data, o.Unique_id_offset, o.Unique_id_len), SourceFile: goString(r.String_data, o.Source_file_offset, o.Source_file_len), EstimatedPayloadCapacity: o.Estimated_payload_capacity, RawPayloadCapacity: o.Raw_payload_capacity, ImagePayloadCapacity: o.Image_payload_capacity, TextPayloadCapacity: o.Text_payload_capacity})
+	}
+	if r.Has_more != 0 {
+		n := int(r.Next_offset)
+		return assets, &n, nil
+	}
+	return assets, nil, nil
+}
+
+func imageReadItems(assets []AssetInfo) []ReadItem {
+	var items []ReadItem
+	for _, a := range assets {
+		if a.Type == "Texture2D" {
+			items = append(items, ReadItem{PathID: a.PathID, Kind: "image", ImageFormat: DefaultImageFormat, PayloadCapacity: readCapacity(a, "image")})
+		}
+	}
+	return items
+}
+
+// SetBufferPool switches direct reads to caller-provided items/payload buffers
+// taken from pool. When the native side reports that a buffer is too small, the
+// buffer grows to the required_* sizes and the batch is retried once. A nil pool
+// lets the native side allocate again. Call it before sharing the Library
+// across goroutines.
+func (l *Library) SetBufferPool(pool *BufferPool) {
+	l.buffers = pool
+}
+
+func (l *Library) readObjectsBatch(contextID int64, readItems []ReadItem) ([]ReadResult, error) {
+	// The item array and its strings are Go memory the native side reads
+	// during the call, so everything stays pinned until the batch is done.
+	var pin runtime.Pinner
+	defer pin.Unpin()
+	items := make([]objectReadItemRequest, len(readItems))
+	pin.Pin(&items[0])
+	type utf8 struct {
+		p *byte
+		n int32
+	}
+	strs := map[string]utf8{}
+	intern := func(s string) utf8 {
+		if v, found := strs[s]; found {
+			return v
+		}
+		var v utf8
+		v.p, v.n = utf8Arg(&pin, s)
+		strs[s] = v
+		return v
+	}
+	for i, it := range readItems {
+		kind := intern(it.Kind)
+		format := intern(it.ImageFormat)
+		items[i] = objectReadItemRequest{Path_id: it.PathID, Kind_utf8: kind.p, Kind_utf8_len: kind.n, Image_format_utf8: format.p, Image_format_utf8_len: format.n}
+	}
+	var bufs *readBuffers
+	if pool := l.buffers; pool != nil {
+		bufs = pool.get(len(readItems), int(l.strides.readItem), batchPayloadCapacity(readItems))
+		defer pool.put(bufs)
+	}
+	for attempt := 0; ; attempt++ {
+		out, retry, err := l.readBatch(contextID, items, bufs, attempt == 0)
+		if retry {
+			continue
+		}
+		return out, err
+	}
+}
+
+// readBatch runs one direct_retry call. With caller buffers and mayRetry set,
+// a too-small failure grows bufs and reports retry instead of an error.
+func (l *Library) readBatch(contextID int64, items []objectReadItemRequest, bufs *readBuffers, mayRetry bool) ([]ReadResult, bool, error) {
+	defer l.gate.enter()()
+	q := objectReadBatchIntoRequestV1{Struct_size: int32(unsafe.Sizeof(objectReadBatchIntoRequestV1{})), Context_id: contextID, Items: &items[0], Count: int32(len(items))}
+	var pin runtime.Pinner
+	defer pin.Unpin()
+	if bufs != nil {
+		if len(bufs.items) > 0 {
+			pin.Pin(&bufs.items[0])
+			q.Items_buffer, q.Items_buffer_len = &bufs.items[0], int64(len(bufs.items))
+		}
+		if len(bufs.payload) > 0 {
+			pin.Pin(&bufs.payload[0])
+			q.Payload, q.Payload_len = &bufs.payload[0], int64(len(bufs.payload))
+		}
+	}
+	var r objectReadBatchRetryResponseV1
+	status := l.native.readRetry(&q, &r)
+	defer func() {
+		if r.Result_handle != 0 {
+			l.native.resultFree(r.Result_handle)
+		}
+	}()
+	grow := func() bool {
+		if bufs == nil {
+			return false
+		}
+		itemTable := int64(len(items)) * int64(l.strides.readItem)
+		return bufs.grow(itemTable, r.Required_items_buffer_len, int64(r.Required_string_data_len), r.Required_payload_len)
+	}
+	if status != ok && status != partialFailure || (r.Status != ok && r.Status != partialFailure) {
+		if mayRetry && grow() {
+			return nil, true, nil
+		}
+		err := callError("read", status, r.Status, r.Error_code, "")
+		if bufs != nil && (r.Required_items_buffer_len > int64(len(bufs.items)) || r.Required_payload_len > int64(len(bufs.payload))) {
+			err.Kind = ErrBufferTooSmall
+		}
+		return nil, false, err
+	}
+	out := make([]ReadResult, 0, int(r.Returned_count))
+	for i := range int(r.Returned_count) {
+		it := nativeElem(r.Items, i, l.strides.readItem)
+		payload, err := payloadBytes(&r, it)
+		if err != nil {
+			return nil, false, err
+		}
+		out = append(out, ReadResult{PathID: it.Path_id, Status: int(it.Status), ErrorCode: int(it.Error_code), PayloadKind: goString(r.String_data, it.Payload_kind_offset, it.Payload_kind_len), SuggestedExtension: goString(r.String_data, it.Suggested_extension_offset, it.Suggested_extension_len), PayloadLen: len(payload), Payload: payload, Error: goString(r.String_data, it.Error_message_offset, it.Error_message_len)})
+	}
+	// The native side may have fallen back to its own storage; size the pooled
+	// buffers so the next batch of this shape fits.
+	grow()
+	if status == partialFailure || r.Status == partialFailure {
+		var message string
+		for _, read := range out {
+			if read.Status != ok {
+				message = read.Error
+				break
+			}
+		}
+		return out, false, callError("read", status, r.Status, r.Error_code, message)
+	}
+	return out, false, nil
+}
+
+func (l *Library) closeNative(contextID int64) error {
+	if err := l.gate.checkContext(contextID); err != nil {
+		return err
+	}
+	defer l.gate.enter()()
+	q := contextCloseRequest{Struct_size: int32(unsafe.Sizeof(contextCloseRequest{})), Context_id: contextID}
+	var r contextCloseResponse
+	status := l.native.contextClose(&q, &r)
+	if status != ok || r.Status != ok {
+		return callError("close", status, r.Status, r.Error_code, "")
+	}
+	l.gate.forgetContext(contextID)
+	return nil
+}
+
+// Inspection is what Inspect learned about a library without rejecting it.
+type Inspection struct {
+	Capabilities Capabilities
+	Limits       Limits
+	// ABIVersions is zero when LayoutError is set.
+	ABIVersions ABIVersions
+	// LayoutError is the ErrVersionMismatch or ErrLayoutMismatch error Load
+	// would fail with, or nil.
+	LayoutError error
+	// DependencyError is the ErrMissingDependency error Load would fail with,
+	// or nil.
+	DependencyError error
+}
+
+// Inspect opens the library at path and reads its capabilities and limits
+// before running the checks Load enforces. Version, layout and dependency
+// problems are recorded in the Inspection instead of failing, so a mismatched
+// or partial build can still be described. Inspect only fails when the library
+// cannot be opened or does not answer the capabilities and limits calls.
+func Inspect(path string, opts LoadOptions) (Inspection, error) {
+	os.Setenv("HARUKI_ASSET_STUDIO_FFI_LIBRARY_PATH", path)
+	depErr := preloadDependencies(path, opts.Dependencies)
+	native, err := openBackend(path)
+	if err != nil {
+		return Inspection{}, err
+	}
+	lib := &Library{native: native}
+	var in Inspection
+	if in.Capabilities, err = lib.Capabilities(); err != nil {
+		return Inspection{}, err
+	}
+	if in.Limits, err = lib.Limits(); err != nil {
+		return Inspection{}, err
+	}
+	in.LayoutError = lib.verifyLayout()
+	in.ABIVersions = lib.ABIVersions()
+	in.DependencyError = lib.dependencyError(opts.Dependencies, depErr)
+	return in, nil
+}
@@ -0,0 +1,48 @@
+package assetstudio
+
+import "testing"
+
+func TestDefaultReadKind(t *testing.T) {
+	for assetType, want := range map[string]string{
+		"Texture2D":       "image",
+		"texture_2d":      "image",
+		"Sprite":          "image",
+		"TextAsset":       "text_bytes",
+		"MonoBehaviour":   "typetree_json",
+		"AudioClip":       "audio",
+		"Mesh":            "obj",
+		"Animator":        "fbx",
+		"SomethingUnseen": "typetree_json",
+	} {
+		if got := DefaultReadKind(assetType); got != want {
+			t.Errorf("DefaultReadKind(%q) = %q, want %q", assetType, got, want)
+		}
+	}
+}
+
+func TestDefaultReadItemsSkipsUntypedAssets(t *testing.T) {
+	assets := []AssetInfo{
+		{PathID: 1, Type: "Texture2D", ImagePayloadCapacity: 99},
+		{PathID: 2, Type: " "},
+		{PathID: 3, Type: "TextAsset", EstimatedPayloadCapacity: 7},
+	}
+	items := DefaultReadItems(assets, DefaultImageFormat)
+	if len(items) != 2 {
+		t.Fatalf("items = %+v", items)
+	}
+	if items[0] != (ReadItem{PathID: 1, Kind: "image", ImageFormat: DefaultImageFormat, PayloadCapacity: 99}) {
+		t.Errorf("items[0] = %+v", items[0])
+	}
+	if items[1] != (ReadItem{PathID: 3, Kind: "text_bytes", ImageFormat: DefaultImageFormat, PayloadCapacity: 7}) {
+		t.Errorf("items[1] = %+v", items[1])
+	}
+}
+
+func TestAssetTypesCSV(t *testing.T) {
+	if got := assetTypesCSV([]string{" Texture2D", "", "Sprite "}); got != "Texture2D,Sprite" {
+		t.Errorf("assetTypesCSV = %q", got)
+	}
+	if got := assetTypesCSV(nil); got != "" {
+		t.Errorf("assetTypesCSV(nil) = %q", got)
+	}
+}
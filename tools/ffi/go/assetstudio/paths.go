@@ -0,0 +1,215 @@
+package assetstudio
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFileStemChars matches ASSETSTUDIO_MAX_PUBLIC_FILE_STEM_CHARS in the Rust
+// export pipeline.
+const maxFileStemChars = 220
+
+// FixFileName makes an asset name safe to use as one path component, the way
+// assetstudio_fix_file_name in export_pipeline/implementation/paths.rs does:
+// reserved and control characters become '_', repeated "(Clone)" suffixes
+// collapse to "__cloneN" and overlong names are truncated.
+func FixFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`<>:"/\|?*`, r), unicode.IsControl(r):
+			return '_'
+		}
+		return r
+	}, name)
+	return shortenFileStem(compressCloneSuffixes(safe))
+}
+
+func compressCloneSuffixes(value string) string {
+	const marker = "(Clone)"
+	end, count := len(value), 0
+	for strings.HasSuffix(value[:end], marker) {
+		end -= len(marker)
+		count++
+	}
+	if count <= 1 {
+		return value
+	}
+	return fmt.Sprintf("%s__clone%d", strings.TrimRightFunc(value[:end], unicode.IsSpace), count)
+}
+
+func shortenFileStem(value string) string {
+	if utf8.RuneCountInString(value) <= maxFileStemChars {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:maxFileStemChars-len("__truncated")]) + "__truncated"
+}
+
+// containerPath turns a container key into a relative slash path: backslashes
+// become slashes, leading slashes go and only normal components are kept, so
+// "../" or "/etc/" in asset metadata cannot leave the output root.
+func containerPath(container string) string {
+	parts := strings.Split(strings.ReplaceAll(container, `\`, "/"), "/")
+	kept := parts[:0]
+	for _, p := range parts {
+		if p != "" && p != "." && p != ".." {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// fileStem names an asset without a container: its name, else its unique id,
+// else its type.
+func fileStem(a AssetInfo) string {
+	for _, candidate := range []string{a.Name, a.UniqueID, a.Type} {
+		if stem := FixFileName(candidate); strings.TrimSpace(stem) != "" && stem != "." && stem != ".." {
+			return stem
+		}
+	}
+	return "asset"
+}
+
+// OutputExtension picks the file extension, without the dot, for a read of a.
+// It follows native_object_output_extension in the Rust export pipeline;
+// image_raw_rgba payloads map to "png" because callers encode them first.
+func OutputExtension(a AssetInfo, r ReadResult) string {
+	known := knownExtension(r.SuggestedExtension)
+	switch strings.ToLower(strings.TrimSpace(r.PayloadKind)) {
+	case "raw":
+		return "dat"
+	case "typetree_json":
+		return "json"
+	case "text_bytes":
+		return cmp.Or(known, "bytes")
+	case "image_bmp":
+		return "bmp"
+	case "image_raw_rgba", "image_png":
+		return "png"
+	case "image_tga":
+		return "tga"
+	case "image_jpeg":
+		return "jpg"
+	case "image_webp":
+		return "webp"
+	case "image_array_bundle_bmp", "image_array_bundle_png", "image_array_bundle_tga", "image_array_bundle_jpeg",
+		"image_array_bundle_webp", "image_array_bundle_raw_rgba", "animator_bundle_fbx":
+		// Multi-file payloads are unpacked into a directory named after the
+		// asset; see IsBundleKind.
+		return ""
+	case "audio_raw":
+		return cmp.Or(known, "wav")
+	case "video_raw":
+		return cmp.Or(known, "bin")
+	case "movie_ogv":
+		return "ogv"
+	case "font":
+		return cmp.Or(known, "ttf")
+	case "shader_text":
+		return "shader"
+	case "mesh_obj":
+		return "obj"
+	}
+	if known != "" {
+		return known
+	}
+	switch normalizeTypeName(a.Type) {
+	case "textasset":
+		return "bytes"
+	case "monobehaviour", "monobehavior":
+		return "json"
+	case "shader", "shadervariantcollection":
+		return "shader"
+	case "mesh":
+		return "obj"
+	case "animator":
+		return "fbx"
+	default:
+		return "dat"
+	}
+}
+
+// IsBundleKind reports whether a payload kind is a payload bundle holding
+// several files (texture array slices, an FBX with its textures) that are
+// written below a directory named after the asset, as the Rust export pipeline
+// does with write_payload_bundle.
+func IsBundleKind(kind string) bool {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	return strings.HasPrefix(kind, "image_array_bundle_") || kind == "animator_bundle_fbx"
+}
+
+// knownExtension accepts a native suggested extension only if it is one the
+// Rust export pipeline also writes.
+func knownExtension(extension string) string {
+	switch e := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(extension), ".")); e {
+	case "bytes", "dat", "json", "lua", "txt", "bmp", "png", "tga", "webp", "wav", "mp3",
+		"flac", "ttf", "otf", "shader", "obj", "fbx", "bin":
+		return e
+	case "jpg", "jpeg":
+		return "jpg"
+	case "ogg", "ogv":
+		return "ogv"
+	}
+	return ""
+}
+
+// OutputPath returns the slash-separated path, relative to an export root, for
+// asset a written with extension ext (without the dot). The path comes from
+// a.Container when it has one, else from a.Name; any extension already on the
+// last component is replaced. The result never contains ".." or a leading
+// slash.
+func OutputPath(a AssetInfo, ext string) string {
+	rel := ""
+	if strings.TrimSpace(a.Container) != "" {
+		rel = containerPath(a.Container)
+	}
+	if rel == "" {
+		rel = fileStem(a)
+	}
+	if ext != "" {
+		if old := path.Ext(rel); old != path.Base(rel) {
+			rel = strings.TrimSuffix(rel, old)
+		}
+		rel += "." + ext
+	}
+	return rel
+}
+
+// OutputPaths hands out unique file paths under Root. A path already claimed
+// in this run gets a "__dupN" suffix on its stem like the Rust export
+// pipeline's semantic_duplicate_path. Files left on disk by an earlier run are
+// not considered, so exporting again overwrites them. It is not safe for
+// concurrent use.
+type OutputPaths struct {
+	Root    string
+	claimed map[string]bool
+}
+
+// Claim reserves rel (as returned by OutputPath) and returns the OS path to
+// write it to.
+func (p *OutputPaths) Claim(rel string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("export path %q escapes the output root", rel)
+	}
+	if p.claimed == nil {
+		p.claimed = map[string]bool{}
+	}
+	ext := path.Ext(rel)
+	stem := strings.TrimSuffix(rel, ext)
+	for ordinal := 1; ; ordinal++ {
+		candidate := rel
+		if ordinal > 1 {
+			candidate = fmt.Sprintf("%s__dup%d%s", stem, ordinal, ext)
+		}
+		if p.claimed[candidate] {
+			continue
+		}
+		p.claimed[candidate] = true
+		return filepath.Join(p.Root, filepath.FromSlash(candidate)), nil
+	}
+}
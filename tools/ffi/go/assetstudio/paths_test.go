@@ -0,0 +1,98 @@
+package assetstudio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixFileName(t *testing.T) {
+	for name, want := range map[string]string{
+		"plain":                        "plain",
+		`a<b>c:d"e/f\g|h?i*j`:          "a_b_c_d_e_f_g_h_i_j",
+		"tab\there":                    "tab_here",
+		"Button(Clone)":                "Button(Clone)",
+		"Button (Clone)(Clone)(Clone)": "Button__clone3",
+	} {
+		if got := FixFileName(name); got != want {
+			t.Errorf("FixFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+	long := FixFileName(strings.Repeat("x", 300))
+	if len(long) != maxFileStemChars || !strings.HasSuffix(long, "__truncated") {
+		t.Errorf("long name shortened to %d chars: %q", len(long), long)
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	for _, tc := range []struct {
+		asset AssetInfo
+		ext   string
+		want  string
+	}{
+		{AssetInfo{Container: "assets/sekai/a.png", Name: "a"}, "png", "assets/sekai/a.png"},
+		{AssetInfo{Container: `/assets\sekai\b.asset`}, "json", "assets/sekai/b.json"},
+		{AssetInfo{Container: "../../etc/./passwd"}, "bytes", "etc/passwd.bytes"},
+		{AssetInfo{Container: "assets/.hidden"}, "json", "assets/.hidden.json"},
+		{AssetInfo{Name: "x/y:z"}, "dat", "x_y_z.dat"},
+		{AssetInfo{Name: "..", UniqueID: "u0"}, "", "u0"},
+		{AssetInfo{Container: "  ", UniqueID: "u1"}, "txt", "u1.txt"},
+		{AssetInfo{Type: "Mesh"}, "obj", "Mesh.obj"},
+		{AssetInfo{}, "", "asset"},
+	} {
+		if got := OutputPath(tc.asset, tc.ext); got != tc.want {
+			t.Errorf("OutputPath(%+v, %q) = %q, want %q", tc.asset, tc.ext, got, tc.want)
+		}
+	}
+}
+
+func TestOutputExtension(t *testing.T) {
+	for _, tc := range []struct {
+		assetType, kind, suggested, want string
+	}{
+		{"Texture2D", "image_raw_rgba", "", "png"},
+		{"TextAsset", "text_bytes", ".lua", "lua"},
+		{"TextAsset", "text_bytes", "exe", "bytes"},
+		{"MonoBehaviour", "typetree_json", "", "json"},
+		{"AudioClip", "audio_raw", "OGG", "ogv"},
+		{"Animator", "", "", "fbx"},
+		{"Animator", "animator_bundle_fbx", ".fbx", ""},
+		{"Texture2DArray", "image_array_bundle_png", ".png", ""},
+		{"Unknown", "", "", "dat"},
+	} {
+		r := ReadResult{PayloadKind: tc.kind, SuggestedExtension: tc.suggested}
+		if got := OutputExtension(AssetInfo{Type: tc.assetType}, r); got != tc.want {
+			t.Errorf("OutputExtension(%s, %s, %q) = %q, want %q", tc.assetType, tc.kind, tc.suggested, got, tc.want)
+		}
+	}
+}
+
+func TestOutputPathsDedupe(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "on-disk.json"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	paths := &OutputPaths{Root: root}
+	for _, tc := range []struct{ rel, want string }{
+		{"a/b.png", "a/b.png"},
+		{"a/b.png", "a/b__dup2.png"},
+		{"a/b.png", "a/b__dup3.png"},
+		{"on-disk.json", "on-disk.json"},
+		{"noext", "noext"},
+		{"noext", "noext__dup2"},
+	} {
+		got, err := paths.Claim(tc.rel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(root, filepath.FromSlash(tc.want)); got != want {
+			t.Errorf("Claim(%q) = %q, want %q", tc.rel, got, want)
+		}
+	}
+	for _, rel := range []string{"../escape", "/abs", ""} {
+		if _, err := paths.Claim(rel); err == nil {
+			t.Errorf("Claim(%q) succeeded", rel)
+		}
+	}
+}
@@ -0,0 +1,706 @@
+package assetstudio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"haruki-assetstudio-go-ffi/rgbair"
+)
+
+// The tests in this file run against testdata/stub.c, a C stand-in for
+// HarukiAssetStudioFFI compiled with the system C compiler on first use.
+var stub struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if stub.path != "" {
+		os.RemoveAll(filepath.Dir(stub.path))
+	}
+	os.Exit(code)
+}
+
+func stubLibrary(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skipf("stub library is not built on %s", runtime.GOOS)
+	}
+	stub.once.Do(func() {
+		cc, err := exec.LookPath("cc")
+		if err != nil {
+			stub.err = err
+			return
+		}
+		dir, err := os.MkdirTemp("", "assetstudio-stub")
+		if err != nil {
+			stub.err = err
+			return
+		}
+		stub.path = filepath.Join(dir, "libHarukiAssetStudioFFIStub.so")
+		args := []string{"-shared", "-fPIC", "-Iinclude", "-o", stub.path, filepath.Join("testdata", "stub.c"), "-lpthread"}
+		if runtime.GOOS == "darwin" {
+			args[0] = "-dynamiclib"
+		}
+		if out, err := exec.Command(cc, args...).CombinedOutput(); err != nil {
+			stub.err = fmt.Errorf("%v: %s", err, out)
+		}
+	})
+	if stub.err != nil {
+		if errors.Is(stub.err, exec.ErrNotFound) {
+			t.Skipf("no C compiler: %v", stub.err)
+		}
+		t.Fatalf("building stub library: %v", stub.err)
+	}
+	return stub.path
+}
+
+// loadStub loads the stub with the given config lines (see testdata/stub.c)
+// and returns the library plus a func that reads the stub's counters.
+func loadStub(t *testing.T, config ...string) (*Library, func() map[string]int64) {
+	t.Helper()
+	path := stubLibrary(t)
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "stub.conf")
+	if err := os.WriteFile(configPath, []byte(strings.Join(config, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	statsPath := filepath.Join(dir, "stub.stats")
+	t.Setenv("HARUKI_ASSETSTUDIO_STUB_CONFIG", configPath)
+	t.Setenv("HARUKI_ASSETSTUDIO_STUB_STATS", statsPath)
+	lib, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := func() map[string]int64 {
+		data, err := os.ReadFile(statsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]int64{}
+		for _, field := range strings.Fields(string(data)) {
+			var n int64
+			key, value, _ := strings.Cut(field, "=")
+			fmt.Sscan(value, &n)
+			out[key] = n
+		}
+		return out
+	}
+	return lib, stats
+}
+
+func openStub(t *testing.T, lib *Library, types []string) *Context {
+	t.Helper()
+	c, err := lib.Open("stub.bundle", "", types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestLoadStub(t *testing.T) {
+	lib, _ := loadStub(t, "limits max_object_read_batch_count=7 max_object_table_page_limit=3")
+	limits, err := lib.Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.MaxObjectReadBatchCount != 7 || limits.MaxObjectTablePageLimit != 3 {
+		t.Errorf("limits = %+v", limits)
+	}
+	caps, err := lib.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.SupportsDirectObjectReadRetry || caps.ObjectReadBatchDirectRetryABIVersion != 1 {
+		t.Errorf("capabilities = %+v", caps)
+	}
+}
+
+func TestLoadMissingLibrary(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestVerifyLayoutRejectsMismatches(t *testing.T) {
+	path := stubLibrary(t)
+	cases := map[string]struct {
+		config string
+		want   error
+	}{
+		"sub-ABI version":   {"caps object_table_abi_version=0", ErrVersionMismatch},
+		"limits version":    {"limits limits_abi_version=2", ErrVersionMismatch},
+		"capabilities size": {"caps struct_size=4", ErrLayoutMismatch},
+		"struct size":       {"caps bad_layout=asset_object", ErrLayoutMismatch},
+	}
+	for name, tc := range cases {
+		config := filepath.Join(t.TempDir(), "stub.conf")
+		if err := os.WriteFile(config, []byte(tc.config+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HARUKI_ASSETSTUDIO_STUB_CONFIG", config)
+		_, err := Load(path)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: Load error = %v, want %v", name, err, tc.want)
+		}
+	}
+}
+
+func TestInspectReportsMismatches(t *testing.T) {
+	path := stubLibrary(t)
+	config := filepath.Join(t.TempDir(), "stub.conf")
+	if err := os.WriteFile(config, []byte("caps bad_layout=asset_object supports_native_dependency_resolver=1\nlimits max_object_read_batch_count=7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HARUKI_ASSETSTUDIO_STUB_CONFIG", config)
+	in, err := Inspect(path, LoadOptions{Dependencies: []string{"libMissingDecoder.so"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Capabilities.SupportsDirectObjectReadRetry || in.Limits.MaxObjectReadBatchCount != 7 {
+		t.Errorf("Inspection = %+v", in)
+	}
+	if !errors.Is(in.LayoutError, ErrLayoutMismatch) {
+		t.Errorf("LayoutError = %v, want ErrLayoutMismatch", in.LayoutError)
+	}
+	if !errors.Is(in.DependencyError, ErrMissingDependency) {
+		t.Errorf("DependencyError = %v, want ErrMissingDependency", in.DependencyError)
+	}
+}
+
+func TestLoadPreloadsDependencies(t *testing.T) {
+	path := stubLibrary(t)
+	dir := t.TempDir()
+	bogus := filepath.Join(dir, "libBogusDecoder.so")
+	if err := os.WriteFile(bogus, []byte("not a shared library"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		config  string
+		deps    []string
+		wantErr []string
+	}{
+		"resolver skips defaults":   {"caps supports_native_dependency_resolver=1", nil, nil},
+		"missing defaults skipped":  {"caps supports_native_dependency_resolver=0", nil, nil},
+		"explicit path":             {"caps supports_native_dependency_resolver=1", []string{path}, nil},
+		"explicit empty list":       {"caps supports_native_dependency_resolver=0", []string{}, nil},
+		"unloadable with dlerror":   {"caps supports_native_dependency_resolver=1", []string{bogus}, []string{bogus, "dlopen"}},
+		"name next to main library": {"caps supports_native_dependency_resolver=1", []string{"libMissingDecoder.so"}, []string{filepath.Join(filepath.Dir(path), "libMissingDecoder.so")}},
+	}
+	for name, tc := range cases {
+		config := filepath.Join(dir, "stub.conf")
+		if err := os.WriteFile(config, []byte(tc.config+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HARUKI_ASSETSTUDIO_STUB_CONFIG", config)
+		_, err := LoadWithOptions(path, LoadOptions{Dependencies: tc.deps})
+		if tc.wantErr == nil {
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrMissingDependency) {
+			t.Errorf("%s: error = %v, want ErrMissingDependency", name, err)
+			continue
+		}
+		for _, want := range tc.wantErr {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", name, err, want)
+			}
+		}
+	}
+}
+
+func TestLoadPreloadsBeforeOpening(t *testing.T) {
+	dir := t.TempDir()
+	bogus := filepath.Join(dir, DefaultDependencies()[0])
+	if err := os.WriteFile(bogus, []byte("not a shared library"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// An explicit dependency fails the load before the main library, which
+	// does not exist here, is even opened.
+	_, err := LoadWithOptions(filepath.Join(dir, "libAbsent.so"), LoadOptions{Dependencies: []string{bogus}})
+	if !errors.Is(err, ErrMissingDependency) {
+		t.Fatalf("error = %v, want ErrMissingDependency", err)
+	}
+
+	// A broken default next to the library only matters when the library
+	// does not resolve its own dependencies.
+	stubData, err := os.ReadFile(stubLibrary(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "libHarukiAssetStudioFFIStub.so")
+	if err := os.WriteFile(path, stubData, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for resolver, wantErr := range map[string]bool{"1": false, "0": true} {
+		config := filepath.Join(dir, "stub.conf")
+		if err := os.WriteFile(config, []byte("caps supports_native_dependency_resolver="+resolver+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HARUKI_ASSETSTUDIO_STUB_CONFIG", config)
+		_, err := Load(path)
+		if got := errors.Is(err, ErrMissingDependency); got != wantErr {
+			t.Errorf("resolver=%s: error = %v", resolver, err)
+		}
+	}
+}
+
+func TestLoadNegotiatesNewerSubABIsDown(t *testing.T) {
+	// v2 object tables and read batches return larger array elements; they
+	// are walked with the native stride and read through their v1 prefix.
+	for _, element := range []string{"asset_object", "object_read_item_response_v1"} {
+		for _, pooled := range []bool{false, true} {
+			lib, _ := loadStub(t,
+				"object path_id=1 type=TextAsset name=first payload=3",
+				"object path_id=2 type=TextAsset name=second payload=5",
+				"object path_id=3 type=MonoBehaviour name=third payload=2",
+				"caps object_table_abi_version=2 object_read_batch_abi_version=2 bad_layout="+element,
+			)
+			if pooled {
+				lib.SetBufferPool(NewBufferPool())
+			}
+			want := ABIVersions{Context: 1, ObjectTable: 1, ObjectTableInto: 1, ObjectReadBatch: 1, ObjectReadBatchInto: 1, ObjectReadBatchDirectRetry: 1}
+			if got := lib.ABIVersions(); got != want {
+				t.Errorf("%s: ABIVersions = %+v, want %+v", element, got, want)
+			}
+			c := openStub(t, lib, nil)
+			assets, err := c.List(nil)
+			if err != nil {
+				t.Fatalf("%s: List: %v", element, err)
+			}
+			var names []string
+			for i, a := range assets {
+				if a.PathID != int64(i+1) || a.Type == "" {
+					t.Errorf("%s: asset %d = %+v", element, i, a)
+				}
+				names = append(names, a.Name)
+			}
+			if got := strings.Join(names, ","); got != "first,second,third" {
+				t.Errorf("%s: names = %q", element, got)
+			}
+			reads, err := c.Read(DefaultReadItems(assets, DefaultImageFormat))
+			if err != nil {
+				t.Fatalf("%s: Read: %v", element, err)
+			}
+			for i, r := range reads {
+				if r.PathID != assets[i].PathID || r.Status != ok || r.PayloadLen != int(assets[i].Size) || r.PayloadKind != "raw" {
+					t.Errorf("%s pooled=%v: read %d = %+v", element, pooled, i, r)
+				}
+			}
+		}
+	}
+}
+
+func TestLoadRejectsLargerStructsOutsideArrays(t *testing.T) {
+	// Only array elements may grow with a downgraded sub-ABI.
+	path := stubLibrary(t)
+	config := filepath.Join(t.TempDir(), "stub.conf")
+	if err := os.WriteFile(config, []byte("caps object_table_abi_version=2 bad_layout=object_table\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HARUKI_ASSETSTUDIO_STUB_CONFIG", config)
+	if _, err := Load(path); !errors.Is(err, ErrLayoutMismatch) {
+		t.Errorf("Load error = %v, want ErrLayoutMismatch", err)
+	}
+}
+
+func TestListPagesThroughObjectTable(t *testing.T) {
+	lib, _ := loadStub(t,
+		"object path_id=10 type=Texture2D name=a container=assets/a.png payload=4",
+		"object path_id=11 type=TextAsset name=b payload=4",
+		"object path_id=12 type=Texture2D name=c payload=4",
+		"object path_id=13 type=MonoBehaviour name=d payload=4",
+		"object path_id=14 type=Texture2D name=e payload=4",
+		"limits max_object_table_page_limit=2",
+	)
+	c := openStub(t, lib, nil)
+	page, next, err := c.ListPage(0, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || next == nil || *next != 2 {
+		t.Fatalf("first page = %+v next = %v", page, next)
+	}
+	assets, err := c.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, a := range assets {
+		ids = append(ids, a.PathID)
+	}
+	if fmt.Sprint(ids) != "[10 11 12 13 14]" {
+		t.Errorf("List path ids = %v", ids)
+	}
+	if assets[0].Name != "a" || assets[0].Container != "assets/a.png" || assets[0].Type != "Texture2D" {
+		t.Errorf("assets[0] = %+v", assets[0])
+	}
+	textures, err := c.List([]string{"Texture2D"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(textures) != 3 {
+		t.Errorf("Texture2D filter returned %d assets", len(textures))
+	}
+}
+
+func TestObjectsFetchesPagesLazily(t *testing.T) {
+	var config []string
+	for id := 1; id <= 10; id++ {
+		config = append(config, fmt.Sprintf("object path_id=%d type=TextAsset name=obj%d payload=1", id, id))
+	}
+	config = append(config, "limits max_object_table_page_limit=3")
+	lib, stats := loadStub(t, config...)
+	c := openStub(t, lib, nil)
+	before := stats()
+	var ids []int64
+	for a, err := range c.Objects(nil, 100) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, a.PathID)
+		if a.Name == "obj4" {
+			break
+		}
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4]" {
+		t.Errorf("path ids before break = %v", ids)
+	}
+	if after := stats(); after["pages_listed"]-before["pages_listed"] != 2 || after["last_list_limit"] != 3 {
+		t.Errorf("stub stats after break = %v, want 2 pages of 3", after)
+	}
+}
+
+func TestObjectsYieldsListErrors(t *testing.T) {
+	lib, _ := loadStub(t, "object path_id=1 type=TextAsset payload=1")
+	c, err := lib.Open("stub.bundle", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, err := range c.Objects(nil, 0) {
+		n++
+		if !errors.Is(err, ErrUnknownContext) {
+			t.Errorf("Objects error = %v, want ErrUnknownContext", err)
+		}
+	}
+	if n != 1 {
+		t.Errorf("Objects yielded %d times after Close, want 1", n)
+	}
+}
+
+func TestReadReportsPartialFailure(t *testing.T) {
+	lib, _ := loadStub(t,
+		"object path_id=1 type=Texture2D width=2 height=1",
+		"object path_id=2 type=TextAsset payload=5",
+		"object path_id=3 type=MonoBehaviour payload=5 fail=1",
+	)
+	c := openStub(t, lib, nil)
+	assets, err := c.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads, err := c.Read(DefaultReadItems(assets, DefaultImageFormat))
+	if !errors.Is(err, ErrPartialFailure) {
+		t.Fatalf("Read error = %v, want ErrPartialFailure", err)
+	}
+	var ffi *FFIError
+	if !errors.As(err, &ffi) || ffi.Message != "stub read failure" {
+		t.Errorf("partial failure error = %#v", err)
+	}
+	if len(reads) != 3 {
+		t.Fatalf("reads = %+v", reads)
+	}
+	if err := reads[2].Err(); err == nil || !strings.Contains(err.Error(), "stub read failure") {
+		t.Errorf("failed item Err() = %v", err)
+	}
+	img, err := rgbair.Decode(reads[0].Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 2 || reads[0].PayloadKind != "image_raw_rgba" {
+		t.Errorf("image read = %+v", reads[0])
+	}
+	if want := []byte{2, 3, 4, 5, 6}; !bytes.Equal(reads[1].Payload, want) {
+		t.Errorf("text payload = %v, want %v", reads[1].Payload, want)
+	}
+}
+
+func TestReadFreesEveryResultHandle(t *testing.T) {
+	var config []string
+	for id := 1; id <= 5; id++ {
+		config = append(config, fmt.Sprintf("object path_id=%d type=TextAsset payload=16", id))
+	}
+	config = append(config, "limits max_object_read_batch_count=2")
+	lib, stats := loadStub(t, config...)
+	c, err := lib.Open("stub.bundle", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets, err := c.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := stats()
+	if _, err := c.Read(DefaultReadItems(assets, DefaultImageFormat)); err != nil {
+		t.Fatal(err)
+	}
+	after := stats()
+	if got := after["results_allocated"] - before["results_allocated"]; got != 3 {
+		t.Errorf("result handles allocated = %d, want 3 (one per sub-batch)", got)
+	}
+	if after["results_outstanding"] != 0 || after["bad_frees"] != 0 || after["results_freed"]-before["results_freed"] != 3 {
+		t.Errorf("stub stats after read = %v", after)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close with no outstanding results: %v", err)
+	}
+}
+
+func TestCallerBuffersSkipNativeResults(t *testing.T) {
+	lib, stats := loadStub(t, "object path_id=1 type=Texture2D width=1024 height=1024")
+	lib.SetBufferPool(NewBufferPool())
+	c := openStub(t, lib, nil)
+	assets, err := c.List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := stats()
+	reads, err := c.ReadImages(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reads) != 1 || reads[0].PayloadLen != rgbair.HeaderLen+1024*1024*4 {
+		t.Fatalf("reads = %+v", reads)
+	}
+	if after := stats(); after["results_allocated"] != before["results_allocated"] {
+		t.Errorf("caller-buffer read allocated a native result: %v", after)
+	}
+}
+
+func TestClosedContextIsUnknown(t *testing.T) {
+	lib, _ := loadStub(t, "object path_id=1 type=TextAsset payload=1")
+	c, err := lib.Open("stub.bundle", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("second Close = %v, want ErrUnknownContext", err)
+	}
+	if _, err := c.List(nil); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("List after Close = %v, want ErrUnknownContext", err)
+	}
+}
+
+func TestOpenStopsAtActiveContextLimit(t *testing.T) {
+	lib, _ := loadStub(t,
+		"caps supports_multiple_contexts=1",
+		"limits max_active_contexts=2",
+	)
+	first := openStub(t, lib, nil)
+	openStub(t, lib, nil)
+	if _, err := lib.Open("stub.bundle", "", nil); err == nil || !strings.Contains(err.Error(), "active context limit reached (2)") {
+		t.Fatalf("third Open = %v, want active context limit error", err)
+	}
+	if got := len(lib.ActiveContexts()); got != 2 {
+		t.Errorf("ActiveContexts = %d, want 2", got)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	openStub(t, lib, nil)
+}
+
+func TestOpenReservesContextWhileOpening(t *testing.T) {
+	// With one context allowed, a second Open started while the first is
+	// still inside context_open is refused rather than racing it.
+	lib, _ := loadStub(t, "caps open_delay_ms=100")
+	opened := make(chan error, 1)
+	go func() {
+		c, err := lib.Open("stub.bundle", "", nil)
+		if err == nil {
+			err = c.Close()
+		}
+		opened <- err
+	}()
+	time.Sleep(30 * time.Millisecond)
+	if _, err := lib.Open("stub.bundle", "", nil); err == nil || !strings.Contains(err.Error(), "active context limit reached (1)") {
+		t.Errorf("concurrent Open = %v, want active context limit error", err)
+	}
+	if err := <-opened; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGateSerializesCallsWithoutConcurrentOperations(t *testing.T) {
+	// The stub sleeps outside its own lock, so overlapping reads only take
+	// the sum of their delays when the gate runs them one at a time.
+	const readers, delay = 3, 50 * time.Millisecond
+	lib, _ := loadStub(t,
+		"object path_id=1 type=TextAsset payload=1",
+		"caps read_delay_ms=50 supports_multiple_contexts=1",
+		"limits max_concurrent_operations=1",
+	)
+	c := openStub(t, lib, nil)
+	items := []ReadItem{{PathID: 1, Kind: "text_bytes"}}
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Read(items)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < readers*delay {
+		t.Errorf("%d concurrent reads took %v, want at least %v when serialized", readers, elapsed, readers*delay)
+	}
+}
+
+func TestGateTreatsZeroConcurrentOperationsAsUnlimited(t *testing.T) {
+	const readers, delay = 3, 100 * time.Millisecond
+	lib, _ := loadStub(t,
+		"object path_id=1 type=TextAsset payload=1",
+		"caps read_delay_ms=100 supports_multiple_contexts=1 supports_concurrent_operations=1",
+		"limits max_concurrent_operations=0",
+	)
+	c := openStub(t, lib, nil)
+	items := []ReadItem{{PathID: 1, Kind: "text_bytes"}}
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Read(items)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= readers*delay {
+		t.Errorf("%d concurrent reads took %v, want them to overlap", readers, elapsed)
+	}
+}
+
+// waitUnquarantined polls until the abandoned native call behind a cancelled
+// method has returned and the Library accepts work again.
+func waitUnquarantined(t *testing.T, lib *Library) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for lib.Quarantined() {
+		if time.Now().After(deadline) {
+			t.Fatal("library still quarantined")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReadContextTimeoutDropsAbandonedResults(t *testing.T) {
+	// Run with -race: the abandoned read finishes after ReadContext returned
+	// and must not write into anything the caller can see.
+	lib, _ := loadStub(t,
+		"object path_id=1 type=TextAsset payload=4",
+		"caps read_delay_ms=300",
+	)
+	c := openStub(t, lib, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reads, err := c.ReadContext(ctx, []ReadItem{{PathID: 1, Kind: "text_bytes"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ReadContext error = %v, want DeadlineExceeded", err)
+	}
+	if len(reads) != 0 {
+		t.Errorf("reads after timeout = %+v, want none", reads)
+	}
+	waitUnquarantined(t, lib)
+	if len(reads) != 0 {
+		t.Errorf("reads changed after the abandoned call returned: %+v", reads)
+	}
+}
+
+func TestCancelMidReadQuarantinesLibrary(t *testing.T) {
+	lib, _ := loadStub(t,
+		"object path_id=1 type=TextAsset payload=4",
+		"caps read_delay_ms=200",
+	)
+	c, err := lib.Open("stub.bundle", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(30*time.Millisecond, cancel)
+	if _, err := c.ReadContext(ctx, []ReadItem{{PathID: 1, Kind: "text_bytes"}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReadContext error = %v, want Canceled", err)
+	}
+	if !lib.Quarantined() {
+		t.Fatal("library not quarantined while the cancelled read runs")
+	}
+	if _, err := c.List(nil); !errors.Is(err, ErrQuarantined) {
+		t.Errorf("List during quarantine = %v, want ErrQuarantined", err)
+	}
+	waitUnquarantined(t, lib)
+	if ids := lib.ActiveContexts(); len(ids) != 0 {
+		t.Errorf("ActiveContexts after quarantine = %v, want the read's context closed", ids)
+	}
+	if _, err := c.List(nil); !errors.Is(err, ErrUnknownContext) {
+		t.Errorf("List on the closed context = %v, want ErrUnknownContext", err)
+	}
+}
+
+func TestCancelMidOpenQuarantinesLibrary(t *testing.T) {
+	lib, _ := loadStub(t, "caps open_delay_ms=200")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(30*time.Millisecond, cancel)
+	if _, err := lib.OpenContext(ctx, "stub.bundle", "", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("OpenContext error = %v, want Canceled", err)
+	}
+	if !lib.Quarantined() {
+		t.Fatal("library not quarantined while the cancelled open runs")
+	}
+	if _, err := lib.Open("stub.bundle", "", nil); !errors.Is(err, ErrQuarantined) {
+		t.Errorf("Open during quarantine = %v, want ErrQuarantined", err)
+	}
+	waitUnquarantined(t, lib)
+	if ids := lib.ActiveContexts(); len(ids) != 0 {
+		t.Errorf("ActiveContexts after quarantine = %v, want the late context closed", ids)
+	}
+	openStub(t, lib, nil)
+}
@@ -0,0 +1,711 @@
+/*
+ * Stub HarukiAssetStudioFFI for hermetic Go tests.
+ *
+ * Exports every haruki_assetstudio_*_v1 symbol with the vendored struct
+ * layouts. The fake object table, limits and capability flags come from the
+ * file named by HARUKI_ASSETSTUDIO_STUB_CONFIG, re-read on every call so each
+ * test can install its own table. One directive per line:
+ *
+ *   object path_id=1 type=Texture2D name=tex container=a/b.png payload=64 fail=0 width=2 height=2 stride=8
+ *   limits max_object_read_batch_count=2 max_object_table_page_limit=3
+ *   caps supports_multiple_contexts=1 object_table_abi_version=2 bad_layout=asset_object
+ *
+ * Objects with fail=1 come back as per-item read failures. bad_layout=NAME
+ * makes abi_layout_v1 report NAME 8 bytes larger than the header says; for
+ * asset_object and object_read_item_response_v1 the arrays are then also
+ * written with that larger stride, the extra bytes filled with 0xAB.
+ *
+ * Result handles are tracked per context: context_close fails while a handle
+ * is still outstanding, and result_free fails on unknown or double frees.
+ * When HARUKI_ASSETSTUDIO_STUB_STATS names a file, the counters are written
+ * there after every object table page, read, result_free and free_buffer as
+ * one line of key=value pairs.
+ */
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <pthread.h>
+#include <unistd.h>
+
+#include "haruki_assetstudio_native.h"
+
+#define STUB_OK 0
+#define STUB_INVALID_ARGUMENT 1
+#define STUB_UNKNOWN_CONTEXT 4
+#define STUB_BUFFER_TOO_SMALL 6
+#define STUB_RESULTS_OUTSTANDING 7
+#define STUB_PARTIAL_FAILURE 9
+#define STUB_READ_FAILED 10
+
+#define MAX_OBJECTS 4096
+#define MAX_TEXT 256
+#define MAX_CONTEXTS 64
+#define MAX_RESULTS 1024
+
+typedef struct stub_object {
+    int64_t path_id;
+    int32_t type_id;
+    char type[MAX_TEXT];
+    char name[MAX_TEXT];
+    char container[MAX_TEXT];
+    int64_t payload;
+    int32_t fail;
+    int32_t width;
+    int32_t height;
+    int32_t stride;
+} stub_object;
+
+typedef struct stub_result {
+    int64_t handle;
+    int64_t context_id;
+    void* items;
+    void* payload;
+} stub_result;
+
+static pthread_mutex_t stub_lock = PTHREAD_MUTEX_INITIALIZER;
+static stub_object objects[MAX_OBJECTS];
+static int object_count;
+static haruki_assetstudio_limits_response limits;
+static haruki_assetstudio_capabilities_response caps;
+static int64_t contexts[MAX_CONTEXTS];
+static int64_t next_context_id = 1;
+static stub_result results[MAX_RESULTS];
+static int64_t next_result_handle = 1;
+static int32_t read_delay_ms;
+static int32_t open_delay_ms;
+static char bad_layout[MAX_TEXT];
+static int64_t results_allocated;
+static int64_t results_freed;
+static int64_t bad_frees;
+static int64_t buffers_freed;
+static int64_t pages_listed;
+static int32_t last_list_limit;
+
+static void set_int(const char* key, const char* value, const char* name, int32_t* dst) {
+    if (strcmp(key, name) == 0) {
+        *dst = (int32_t)strtol(value, NULL, 10);
+    }
+}
+
+static void set_long(const char* key, const char* value, const char* name, int64_t* dst) {
+    if (strcmp(key, name) == 0) {
+        *dst = (int64_t)strtoll(value, NULL, 10);
+    }
+}
+
+static void set_text(const char* key, const char* value, const char* name, char* dst) {
+    if (strcmp(key, name) == 0) {
+        snprintf(dst, MAX_TEXT, "%s", value);
+    }
+}
+
+static void default_config(void) {
+    object_count = 0;
+    read_delay_ms = 0;
+    open_delay_ms = 0;
+    bad_layout[0] = 0;
+    memset(&limits, 0, sizeof(limits));
+    limits.struct_size = sizeof(limits);
+    limits.abi_version = 1;
+    limits.schema_version = 1;
+    limits.limits_abi_version = 1;
+    limits.max_native_utf8_bytes = 1 << 20;
+    limits.max_object_read_batch_count = 1024;
+    limits.max_object_table_page_limit = 4096;
+    limits.max_object_read_batch_payload_bytes = 1 << 30;
+    limits.max_cached_object_read_batch_payload_bytes = 1 << 30;
+    limits.max_active_contexts = MAX_CONTEXTS;
+    limits.max_concurrent_operations = 1;
+    memset(&caps, 0, sizeof(caps));
+    caps.struct_size = sizeof(caps);
+    caps.abi_version = 1;
+    caps.schema_version = 1;
+    caps.core_api_version_major = 1;
+    caps.context_abi_version = 1;
+    caps.object_table_abi_version = 1;
+    caps.object_table_into_abi_version = 1;
+    caps.object_read_batch_abi_version = 1;
+    caps.object_read_batch_into_abi_version = 1;
+    caps.object_read_batch_direct_retry_abi_version = 1;
+    caps.supports_typed_object_table = 1;
+    caps.supports_caller_provided_object_table_buffers = 1;
+    caps.supports_typed_object_read = 1;
+    caps.supports_typed_object_read_batch = 1;
+    caps.supports_result_handle = 1;
+    caps.supports_direct_object_read_retry = 1;
+    caps.supports_typed_context = 1;
+    caps.supports_native_dependency_resolver = 1;
+    caps.supports_abi_layout = 1;
+}
+
+static void apply_directive(char* line) {
+    char* save = NULL;
+    char* word = strtok_r(line, " \t\r\n", &save);
+    if (word == NULL || word[0] == '#') {
+        return;
+    }
+    stub_object* object = NULL;
+    if (strcmp(word, "object") == 0) {
+        if (object_count >= MAX_OBJECTS) {
+            return;
+        }
+        object = &objects[object_count++];
+        memset(object, 0, sizeof(*object));
+    }
+    while ((word = strtok_r(NULL, " \t\r\n", &save)) != NULL) {
+        char* eq = strchr(word, '=');
+        if (eq == NULL) {
+            continue;
+        }
+        *eq = 0;
+        const char* key = word;
+        const char* value = eq + 1;
+        if (object != NULL) {
+            set_long(key, value, "path_id", &object->path_id);
+            set_int(key, value, "type_id", &object->type_id);
+            set_text(key, value, "type", object->type);
+            set_text(key, value, "name", object->name);
+            set_text(key, value, "container", object->container);
+            set_long(key, value, "payload", &object->payload);
+            set_int(key, value, "fail", &object->fail);
+            set_int(key, value, "width", &object->width);
+            set_int(key, value, "height", &object->height);
+            set_int(key, value, "stride", &object->stride);
+            continue;
+        }
+        set_int(key, value, "read_delay_ms", &read_delay_ms);
+        set_int(key, value, "open_delay_ms", &open_delay_ms);
+        set_text(key, value, "bad_layout", bad_layout);
+        set_int(key, value, "struct_size", &caps.struct_size);
+        set_int(key, value, "max_native_utf8_bytes", &limits.max_native_utf8_bytes);
+        set_int(key, value, "max_object_read_batch_count", &limits.max_object_read_batch_count);
+        set_int(key, value, "max_object_table_page_limit", &limits.max_object_table_page_limit);
+        set_long(key, value, "max_object_read_batch_payload_bytes", &limits.max_object_read_batch_payload_bytes);
+        set_int(key, value, "max_active_contexts", &limits.max_active_contexts);
+        set_int(key, value, "max_concurrent_operations", &limits.max_concurrent_operations);
+        set_int(key, value, "supports_multiple_contexts", &caps.supports_multiple_contexts);
+        set_int(key, value, "supports_concurrent_operations", &caps.supports_concurrent_operations);
+        set_int(key, value, "supports_native_dependency_resolver", &caps.supports_native_dependency_resolver);
+        set_int(key, value, "abi_version", &caps.abi_version);
+        set_int(key, value, "context_abi_version", &caps.context_abi_version);
+        set_int(key, value, "object_table_abi_version", &caps.object_table_abi_version);
+        set_int(key, value, "object_table_into_abi_version", &caps.object_table_into_abi_version);
+        set_int(key, value, "object_read_batch_abi_version", &caps.object_read_batch_abi_version);
+        set_int(key, value, "object_read_batch_into_abi_version", &caps.object_read_batch_into_abi_version);
+        set_int(key, value, "object_read_batch_direct_retry_abi_version", &caps.object_read_batch_direct_retry_abi_version);
+        set_int(key, value, "limits_abi_version", &limits.limits_abi_version);
+    }
+    limits.supports_multiple_contexts = caps.supports_multiple_contexts;
+    limits.supports_concurrent_operations = caps.supports_concurrent_operations;
+}
+
+/* Called with stub_lock held. */
+static void reload_config(void) {
+    default_config();
+    const char* path = getenv("HARUKI_ASSETSTUDIO_STUB_CONFIG");
+    if (path == NULL || path[0] == 0) {
+        return;
+    }
+    FILE* file = fopen(path, "r");
+    if (file == NULL) {
+        return;
+    }
+    char line[2048];
+    while (fgets(line, sizeof(line), file) != NULL) {
+        apply_directive(line);
+    }
+    fclose(file);
+}
+
+/* Called with stub_lock held. */
+static void write_stats(void) {
+    const char* path = getenv("HARUKI_ASSETSTUDIO_STUB_STATS");
+    if (path == NULL || path[0] == 0) {
+        return;
+    }
+    int64_t outstanding = 0;
+    for (int i = 0; i < MAX_RESULTS; i++) {
+        outstanding += results[i].handle != 0;
+    }
+    FILE* file = fopen(path, "w");
+    if (file == NULL) {
+        return;
+    }
+    fprintf(file, "results_allocated=%lld results_freed=%lld results_outstanding=%lld bad_frees=%lld buffers_freed=%lld pages_listed=%lld last_list_limit=%d\n",
+        (long long)results_allocated, (long long)results_freed, (long long)outstanding, (long long)bad_frees, (long long)buffers_freed, (long long)pages_listed, last_list_limit);
+    fclose(file);
+}
+
+/* Extra bytes per element bad_layout adds to the struct called name. */
+static size_t layout_pad(const char* name) {
+    return strcmp(bad_layout, name) == 0 ? 8 : 0;
+}
+
+static int find_context(int64_t context_id) {
+    for (int i = 0; i < MAX_CONTEXTS; i++) {
+        if (contexts[i] == context_id && context_id != 0) {
+            return i;
+        }
+    }
+    return -1;
+}
+
+static int type_selected(const stub_object* object, const uint8_t* csv, int32_t csv_len) {
+    if (csv == NULL || csv_len <= 0) {
+        return 1;
+    }
+    size_t type_len = strlen(object->type);
+    int32_t start = 0;
+    for (int32_t i = 0; i <= csv_len; i++) {
+        if (i == csv_len || csv[i] == ',') {
+            if ((size_t)(i - start) == type_len && memcmp(csv + start, object->type, type_len) == 0) {
+                return 1;
+            }
+            start = i + 1;
+        }
+    }
+    return 0;
+}
+
+static stub_object* find_object(int64_t path_id) {
+    for (int i = 0; i < object_count; i++) {
+        if (objects[i].path_id == path_id) {
+            return &objects[i];
+        }
+    }
+    return NULL;
+}
+
+static int is_image_read(const haruki_assetstudio_object_read_item_request* item) {
+    return item->kind_utf8_len == 5 && memcmp(item->kind_utf8, "image", 5) == 0;
+}
+
+static int64_t object_payload_len(const stub_object* object, const haruki_assetstudio_object_read_item_request* item) {
+    if (is_image_read(item) && object->width > 0 && object->height > 0) {
+        int64_t stride = object->stride > 0 ? object->stride : (int64_t)object->width * 4;
+        return 36 + stride * object->height;
+    }
+    return object->payload;
+}
+
+static void fill_payload(uint8_t* dst, const stub_object* object, const haruki_assetstudio_object_read_item_request* item, int64_t len) {
+    if (is_image_read(item) && object->width > 0 && object->height > 0) {
+        int64_t stride = object->stride > 0 ? object->stride : (int64_t)object->width * 4;
+        uint32_t header[5] = {(uint32_t)object->width, (uint32_t)object->height, (uint32_t)stride, 1, 0};
+        memcpy(dst, "HARUKI_RGBAIR_V1", 16);
+        memcpy(dst + 16, header, sizeof(header));
+        for (int64_t i = 36; i < len; i++) {
+            dst[i] = (uint8_t)((object->path_id + i - 36) & 0xff);
+        }
+        return;
+    }
+    for (int64_t i = 0; i < len; i++) {
+        dst[i] = (uint8_t)((object->path_id + i) & 0xff);
+    }
+}
+
+int32_t haruki_assetstudio_capabilities_v1(haruki_assetstudio_capabilities_response* response) {
+    pthread_mutex_lock(&stub_lock);
+    reload_config();
+    *response = caps;
+    pthread_mutex_unlock(&stub_lock);
+    return STUB_OK;
+}
+
+int32_t haruki_assetstudio_abi_layout_v1(haruki_assetstudio_abi_layout_response* response) {
+    pthread_mutex_lock(&stub_lock);
+    reload_config();
+    pthread_mutex_unlock(&stub_lock);
+    memset(response, 0, sizeof(*response));
+    response->struct_size = sizeof(*response);
+    response->abi_version = 1;
+    response->schema_version = 1;
+    response->layout_version = 1;
+    response->context_open_request = sizeof(haruki_assetstudio_context_open_request);
+    response->context_open_response = sizeof(haruki_assetstudio_context_open_response);
+    response->context_close_request = sizeof(haruki_assetstudio_context_close_request);
+    response->context_close_response = sizeof(haruki_assetstudio_context_close_response);
+    response->limits_response = sizeof(haruki_assetstudio_limits_response);
+    response->capabilities_response = sizeof(haruki_assetstudio_capabilities_response);
+    response->object_list_request = sizeof(haruki_assetstudio_object_list_request);
+    response->object_list_into_request_v1 = sizeof(haruki_assetstudio_object_list_into_request_v1);
+    response->object_table = sizeof(haruki_assetstudio_object_table);
+    response->asset_object = sizeof(haruki_assetstudio_asset_object);
+    response->object_read_item_request = sizeof(haruki_assetstudio_object_read_item_request);
+    response->object_read_batch_into_request_v1 = sizeof(haruki_assetstudio_object_read_batch_into_request_v1);
+    response->object_read_item_response_v1 = sizeof(haruki_assetstudio_object_read_item_response_v1);
+    response->object_read_batch_retry_response_v1 = sizeof(haruki_assetstudio_object_read_batch_retry_response_v1);
+    const char* names[] = {"context_open_request", "context_open_response", "limits_response", "capabilities_response", "object_table", "asset_object", "object_read_item_request", "object_read_batch_into_request_v1", "object_read_item_response_v1", "object_read_batch_retry_response_v1"};
+    int32_t* sizes[] = {&response->context_open_request, &response->context_open_response, &response->limits_response, &response->capabilities_response, &response->object_table, &response->asset_object, &response->object_read_item_request, &response->object_read_batch_into_request_v1, &response->object_read_item_response_v1, &response->object_read_batch_retry_response_v1};
+    for (size_t i = 0; i < sizeof(names) / sizeof(names[0]); i++) {
+        if (strcmp(bad_layout, names[i]) == 0) {
+            *sizes[i] += 8;
+        }
+    }
+    return STUB_OK;
+}
+
+int32_t haruki_assetstudio_limits_v1(haruki_assetstudio_limits_response* response) {
+    pthread_mutex_lock(&stub_lock);
+    reload_config();
+    *response = limits;
+    pthread_mutex_unlock(&stub_lock);
+    return STUB_OK;
+}
+
+int32_t haruki_assetstudio_context_open_v1(const haruki_assetstudio_context_open_request* request, haruki_assetstudio_context_open_response* response) {
+    memset(response, 0, sizeof(*response));
+    response->struct_size = sizeof(*response);
+    response->abi_version = 1;
+    response->schema_version = 1;
+    response->context_abi_version = 1;
+    if (request == NULL || request->struct_size != (int32_t)sizeof(*request) || request->input_path_utf8 == NULL || request->input_path_utf8_len <= 0) {
+        response->status = STUB_INVALID_ARGUMENT;
+        response->error_code = STUB_INVALID_ARGUMENT;
+        return STUB_INVALID_ARGUMENT;
+    }
+    pthread_mutex_lock(&stub_lock);
+    reload_config();
+    int32_t delay_ms = open_delay_ms;
+    pthread_mutex_unlock(&stub_lock);
+    if (delay_ms > 0) {
+        usleep((useconds_t)delay_ms * 1000);
+    }
+    pthread_mutex_lock(&stub_lock);
+    int slot = find_context(0);
+    for (int i = 0; i < MAX_CONTEXTS && slot < 0; i++) {
+        if (contexts[i] == 0) {
+            slot = i;
+        }
+    }
+    int active = 0;
+    for (int i = 0; i < MAX_CONTEXTS; i++) {
+        active += contexts[i] != 0;
+    }
+    if (slot < 0 || active >= limits.max_active_contexts) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_INVALID_ARGUMENT;
+        response->error_code = STUB_INVALID_ARGUMENT;
+        return STUB_INVALID_ARGUMENT;
+    }
+    contexts[slot] = next_context_id++;
+    response->context_id = contexts[slot];
+    int selected = 0;
+    for (int i = 0; i < object_count; i++) {
+        selected += type_selected(&objects[i], request->asset_types_csv_utf8, request->asset_types_csv_utf8_len);
+    }
+    pthread_mutex_unlock(&stub_lock);
+    response->assets_file_count = 1;
+    response->exportable_asset_count = selected;
+    response->object_index_count = selected;
+    response->buffer = malloc(8);
+    response->buffer_len = 8;
+    return STUB_OK;
+}
+
+static int32_t list_objects(int64_t context_id, int32_t offset, int32_t limit, const uint8_t* csv, int32_t csv_len, uint8_t* buffer, int64_t buffer_len, haruki_assetstudio_object_table* response) {
+    memset(response, 0, sizeof(*response));
+    response->struct_size = sizeof(*response);
+    response->abi_version = 1;
+    response->schema_version = 1;
+    response->object_table_abi_version = 1;
+    response->context_id = context_id;
+    pthread_mutex_lock(&stub_lock);
+    reload_config();
+    if (find_context(context_id) < 0) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_UNKNOWN_CONTEXT;
+        response->error_code = STUB_UNKNOWN_CONTEXT;
+        return STUB_UNKNOWN_CONTEXT;
+    }
+    if (buffer != NULL) {
+        last_list_limit = limit;
+    }
+    if (limit <= 0 || limit > limits.max_object_table_page_limit) {
+        limit = limits.max_object_table_page_limit;
+    }
+    int selected[MAX_OBJECTS];
+    int total = 0;
+    for (int i = 0; i < object_count; i++) {
+        if (type_selected(&objects[i], csv, csv_len)) {
+            selected[total++] = i;
+        }
+    }
+    int start = offset < 0 ? 0 : offset;
+    if (start > total) {
+        start = total;
+    }
+    int end = start + limit > total ? total : start + limit;
+    int count = end - start;
+    int64_t strings = 0;
+    for (int i = start; i < end; i++) {
+        const stub_object* object = &objects[selected[i]];
+        strings += strlen(object->name) + strlen(object->container) + strlen(object->type);
+    }
+    size_t object_stride = sizeof(haruki_assetstudio_asset_object) + layout_pad("asset_object");
+    int64_t table_bytes = (int64_t)count * object_stride;
+    response->offset = start;
+    response->limit = limit;
+    response->total_count = total;
+    response->returned_count = count;
+    response->has_more = end < total;
+    response->next_offset = end < total ? end : -1;
+    response->buffer_len = table_bytes + strings;
+    response->string_data_len = (int32_t)strings;
+    if (buffer == NULL) {
+        pthread_mutex_unlock(&stub_lock);
+        return STUB_OK;
+    }
+    if (buffer_len < table_bytes + strings) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_BUFFER_TOO_SMALL;
+        response->error_code = STUB_BUFFER_TOO_SMALL;
+        return STUB_BUFFER_TOO_SMALL;
+    }
+    uint8_t* string_data = buffer + table_bytes;
+    int32_t cursor = 0;
+    for (int i = start; i < end; i++) {
+        const stub_object* object = &objects[selected[i]];
+        haruki_assetstudio_asset_object* out = (haruki_assetstudio_asset_object*)(buffer + (size_t)(i - start) * object_stride);
+        memset(out, 0xAB, object_stride);
+        memset(out, 0, sizeof(*out));
+        out->index = selected[i];
+        out->type_id = object->type_id;
+        out->path_id = object->path_id;
+        out->size = object->payload;
+        out->estimated_payload_capacity = object->payload;
+        out->raw_payload_capacity = object->payload;
+        if (object->width > 0 && object->height > 0) {
+            int64_t stride = object->stride > 0 ? object->stride : (int64_t)object->width * 4;
+            out->image_payload_capacity = 36 + stride * object->height;
+            out->estimated_payload_capacity = out->image_payload_capacity;
+        }
+        const char* fields[3] = {object->name, object->container, object->type};
+        int32_t* offsets[3] = {&out->name_offset, &out->container_offset, &out->type_offset};
+        int32_t* lens[3] = {&out->name_len, &out->container_len, &out->type_len};
+        for (int f = 0; f < 3; f++) {
+            int32_t len = (int32_t)strlen(fields[f]);
+            *offsets[f] = cursor;
+            *lens[f] = len;
+            memcpy(string_data + cursor, fields[f], len);
+            cursor += len;
+        }
+    }
+    pages_listed++;
+    write_stats();
+    pthread_mutex_unlock(&stub_lock);
+    response->objects = (haruki_assetstudio_asset_object*)buffer;
+    response->string_data = string_data;
+    response->buffer = buffer;
+    return STUB_OK;
+}
+
+int32_t haruki_assetstudio_context_list_objects_size_v1(const haruki_assetstudio_object_list_request* request, haruki_assetstudio_object_table* response) {
+    return list_objects(request->context_id, request->offset, request->limit, request->asset_types_csv_utf8, request->asset_types_csv_utf8_len, NULL, 0, response);
+}
+
+int32_t haruki_assetstudio_context_list_objects_into_v1(const haruki_assetstudio_object_list_into_request_v1* request, haruki_assetstudio_object_table* response) {
+    if (request->buffer == NULL) {
+        memset(response, 0, sizeof(*response));
+        response->struct_size = sizeof(*response);
+        response->status = STUB_INVALID_ARGUMENT;
+        response->error_code = STUB_INVALID_ARGUMENT;
+        return STUB_INVALID_ARGUMENT;
+    }
+    return list_objects(request->context_id, request->offset, request->limit, request->asset_types_csv_utf8, request->asset_types_csv_utf8_len, request->buffer, request->buffer_len, response);
+}
+
+static const char read_error[] = "stub read failure";
+static const char image_kind[] = "image_raw_rgba";
+static const char raw_kind[] = "raw";
+static const char image_ext[] = ".png";
+static const char raw_ext[] = ".bytes";
+
+int32_t haruki_assetstudio_context_read_objects_direct_retry_v1(const haruki_assetstudio_object_read_batch_into_request_v1* request, haruki_assetstudio_object_read_batch_retry_response_v1* response) {
+    memset(response, 0, sizeof(*response));
+    response->struct_size = sizeof(*response);
+    response->abi_version = 1;
+    response->schema_version = 1;
+    response->object_read_batch_abi_version = 1;
+    response->object_read_batch_into_abi_version = 1;
+    response->object_read_batch_direct_retry_abi_version = 1;
+    response->context_id = request->context_id;
+    response->requested_count = request->count;
+    pthread_mutex_lock(&stub_lock);
+    reload_config();
+    int32_t delay_ms = read_delay_ms;
+    pthread_mutex_unlock(&stub_lock);
+    if (delay_ms > 0) {
+        usleep((useconds_t)delay_ms * 1000);
+    }
+    pthread_mutex_lock(&stub_lock);
+    if (find_context(request->context_id) < 0) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_UNKNOWN_CONTEXT;
+        response->error_code = STUB_UNKNOWN_CONTEXT;
+        return STUB_UNKNOWN_CONTEXT;
+    }
+    if (request->count <= 0 || request->count > limits.max_object_read_batch_count) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_INVALID_ARGUMENT;
+        response->error_code = STUB_INVALID_ARGUMENT;
+        return STUB_INVALID_ARGUMENT;
+    }
+    int32_t count = request->count;
+    const char* strings = "image_raw_rgbarawstub read failure.png.bytes";
+    int32_t string_len = (int32_t)strlen(strings);
+    int32_t image_kind_offset = 0;
+    int32_t raw_kind_offset = (int32_t)strlen(image_kind);
+    int32_t error_offset = raw_kind_offset + (int32_t)strlen(raw_kind);
+    int32_t image_ext_offset = error_offset + (int32_t)strlen(read_error);
+    int32_t raw_ext_offset = image_ext_offset + (int32_t)strlen(image_ext);
+    int64_t payload_len = 0;
+    int32_t failed = 0;
+    for (int32_t i = 0; i < count; i++) {
+        stub_object* object = find_object(request->items[i].path_id);
+        if (object == NULL || object->fail) {
+            failed++;
+            continue;
+        }
+        payload_len += object_payload_len(object, &request->items[i]);
+    }
+    size_t item_stride = sizeof(haruki_assetstudio_object_read_item_response_v1) + layout_pad("object_read_item_response_v1");
+    int64_t items_bytes = (int64_t)count * item_stride;
+    response->required_items_buffer_len = items_bytes + string_len;
+    response->required_string_data_len = string_len;
+    response->required_payload_len = payload_len;
+    int caller_buffers = request->items_buffer != NULL || request->payload != NULL;
+    if (payload_len > limits.max_object_read_batch_payload_bytes) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_INVALID_ARGUMENT;
+        response->error_code = STUB_INVALID_ARGUMENT;
+        return STUB_INVALID_ARGUMENT;
+    }
+    if (caller_buffers && (request->items_buffer_len < items_bytes + string_len || request->payload_len < payload_len)) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_BUFFER_TOO_SMALL;
+        response->error_code = STUB_BUFFER_TOO_SMALL;
+        return STUB_BUFFER_TOO_SMALL;
+    }
+    uint8_t* items_buffer = caller_buffers ? request->items_buffer : malloc(items_bytes + string_len);
+    uint8_t* payload = caller_buffers ? request->payload : malloc(payload_len > 0 ? payload_len : 1);
+    uint8_t* string_data = items_buffer + items_bytes;
+    memcpy(string_data, strings, string_len);
+    int64_t cursor = 0;
+    for (int32_t i = 0; i < count; i++) {
+        const haruki_assetstudio_object_read_item_request* item = &request->items[i];
+        stub_object* object = find_object(item->path_id);
+        haruki_assetstudio_object_read_item_response_v1* out = (haruki_assetstudio_object_read_item_response_v1*)(items_buffer + (size_t)i * item_stride);
+        memset(out, 0xAB, item_stride);
+        memset(out, 0, sizeof(*out));
+        out->index = i;
+        out->path_id = item->path_id;
+        if (object == NULL || object->fail) {
+            out->status = STUB_READ_FAILED;
+            out->error_code = STUB_READ_FAILED;
+            out->error_message_offset = error_offset;
+            out->error_message_len = (int32_t)strlen(read_error);
+            continue;
+        }
+        int64_t len = object_payload_len(object, item);
+        out->type_id = object->type_id;
+        out->size = object->payload;
+        out->payload_offset = cursor;
+        out->payload_len = len;
+        int image = is_image_read(item) && object->width > 0;
+        out->payload_kind_offset = image ? image_kind_offset : raw_kind_offset;
+        out->payload_kind_len = (int32_t)strlen(image ? image_kind : raw_kind);
+        out->suggested_extension_offset = image ? image_ext_offset : raw_ext_offset;
+        out->suggested_extension_len = (int32_t)strlen(image ? image_ext : raw_ext);
+        fill_payload(payload + cursor, object, item, len);
+        cursor += len;
+    }
+    response->returned_count = count;
+    response->failed_count = failed;
+    response->items = (haruki_assetstudio_object_read_item_response_v1*)items_buffer;
+    response->string_data = string_data;
+    response->string_data_len = string_len;
+    response->items_buffer = items_buffer;
+    response->items_buffer_len = items_bytes + string_len;
+    response->payload = payload;
+    response->payload_len = payload_len;
+    if (!caller_buffers) {
+        for (int i = 0; i < MAX_RESULTS; i++) {
+            if (results[i].handle == 0) {
+                results[i].handle = next_result_handle++;
+                results[i].context_id = request->context_id;
+                results[i].items = items_buffer;
+                results[i].payload = payload;
+                response->result_handle = results[i].handle;
+                results_allocated++;
+                break;
+            }
+        }
+    }
+    write_stats();
+    pthread_mutex_unlock(&stub_lock);
+    int32_t status = failed == 0 ? STUB_OK : STUB_PARTIAL_FAILURE;
+    response->status = status;
+    response->error_code = failed == 0 ? STUB_OK : STUB_READ_FAILED;
+    return status;
+}
+
+int32_t haruki_assetstudio_context_close_v1(const haruki_assetstudio_context_close_request* request, haruki_assetstudio_context_close_response* response) {
+    memset(response, 0, sizeof(*response));
+    response->struct_size = sizeof(*response);
+    response->abi_version = 1;
+    response->schema_version = 1;
+    response->context_abi_version = 1;
+    response->context_id = request->context_id;
+    pthread_mutex_lock(&stub_lock);
+    int slot = find_context(request->context_id);
+    if (slot < 0) {
+        pthread_mutex_unlock(&stub_lock);
+        response->status = STUB_UNKNOWN_CONTEXT;
+        response->error_code = STUB_UNKNOWN_CONTEXT;
+        return STUB_UNKNOWN_CONTEXT;
+    }
+    for (int i = 0; i < MAX_RESULTS; i++) {
+        if (results[i].handle != 0 && results[i].context_id == request->context_id) {
+            pthread_mutex_unlock(&stub_lock);
+            response->status = STUB_RESULTS_OUTSTANDING;
+            response->error_code = STUB_RESULTS_OUTSTANDING;
+            return STUB_RESULTS_OUTSTANDING;
+        }
+    }
+    contexts[slot] = 0;
+    pthread_mutex_unlock(&stub_lock);
+    return STUB_OK;
+}
+
+int32_t haruki_assetstudio_result_free(int64_t result_handle) {
+    pthread_mutex_lock(&stub_lock);
+    for (int i = 0; i < MAX_RESULTS; i++) {
+        if (results[i].handle == result_handle && result_handle != 0) {
+            free(results[i].items);
+            free(results[i].payload);
+            memset(&results[i], 0, sizeof(results[i]));
+            results_freed++;
+            write_stats();
+            pthread_mutex_unlock(&stub_lock);
+            return STUB_OK;
+        }
+    }
+    bad_frees++;
+    write_stats();
+    pthread_mutex_unlock(&stub_lock);
+    return STUB_INVALID_ARGUMENT;
+}
+
+void haruki_assetstudio_free_buffer(uint8_t* buffer) {
+    free(buffer);
+    pthread_mutex_lock(&stub_lock);
+    buffers_freed++;
+    write_stats();
+    pthread_mutex_unlock(&stub_lock);
+}
+
+void haruki_assetstudio_free_string(char* value) {
+    free(value);
+}
@@ -0,0 +1,83 @@
+package assetstudio
+
+import "fmt"
+
+// abiRange is the span of versions of one sub-ABI this package can speak.
+type abiRange struct {
+	min, max int
+}
+
+// subABI is one independently versioned part of the typed ABI, as advertised
+// in haruki_assetstudio_capabilities_response.
+type subABI struct {
+	name      string
+	supported abiRange
+	native    func(*capabilitiesResponse) int32
+	version   func(*ABIVersions) *int
+}
+
+// subABIs lists the sub-ABIs Load negotiates. Each one speaks the v1 struct
+// shapes in abi.go; a new shape gets its own mirror, a wider range here and a
+// switch on the negotiated version where the struct is used.
+var subABIs = []subABI{
+	{"context", abiRange{1, 1},
+		func(c *capabilitiesResponse) int32 { return c.Context_abi_version },
+		func(v *ABIVersions) *int { return &v.Context }},
+	{"object_table", abiRange{1, 1},
+		func(c *capabilitiesResponse) int32 { return c.Object_table_abi_version },
+		func(v *ABIVersions) *int { return &v.ObjectTable }},
+	{"object_table_into", abiRange{1, 1},
+		func(c *capabilitiesResponse) int32 { return c.Object_table_into_abi_version },
+		func(v *ABIVersions) *int { return &v.ObjectTableInto }},
+	{"object_read_batch", abiRange{1, 1},
+		func(c *capabilitiesResponse) int32 { return c.Object_read_batch_abi_version },
+		func(v *ABIVersions) *int { return &v.ObjectReadBatch }},
+	{"object_read_batch_into", abiRange{1, 1},
+		func(c *capabilitiesResponse) int32 { return c.Object_read_batch_into_abi_version },
+		func(v *ABIVersions) *int { return &v.ObjectReadBatchInto }},
+	{"object_read_batch_direct_retry", abiRange{1, 1},
+		func(c *capabilitiesResponse) int32 { return c.Object_read_batch_direct_retry_abi_version },
+		func(v *ABIVersions) *int { return &v.ObjectReadBatchDirectRetry }},
+}
+
+// arrayElementSubABI names the sub-ABI that fixes the shape of each struct
+// the native side returns as an array. When that sub-ABI is negotiated down,
+// the library's larger element size is accepted and used as the stride;
+// every other struct in haruki_assetstudio_abi_layout_response must match
+// the Go mirror exactly.
+var arrayElementSubABI = map[string]string{
+	"asset_object":                 "object_table",
+	"object_read_item_response_v1": "object_read_batch",
+}
+
+// ABIVersions are the sub-ABI versions Load settled on: the newest version
+// both the native library and this package support.
+type ABIVersions struct {
+	Context                    int `json:"context"`
+	ObjectTable                int `json:"object_table"`
+	ObjectTableInto            int `json:"object_table_into"`
+	ObjectReadBatch            int `json:"object_read_batch"`
+	ObjectReadBatchInto        int `json:"object_read_batch_into"`
+	ObjectReadBatchDirectRetry int `json:"object_read_batch_direct_retry"`
+}
+
+// negotiateABI picks min(native, max) for every sub-ABI and fails with
+// ErrVersionMismatch when the library only offers versions older than this
+// package supports. downgraded holds the sub-ABIs where the library is newer
+// than the version picked: the array elements it returns for those may be
+// larger than the Go mirrors.
+func negotiateABI(caps *capabilitiesResponse) (versions ABIVersions, downgraded map[string]bool, err error) {
+	downgraded = map[string]bool{}
+	for _, s := range subABIs {
+		native := int(s.native(caps))
+		if native < s.supported.min {
+			return ABIVersions{}, nil, fmt.Errorf("%s %w native=%d go=%d..%d", s.name, ErrVersionMismatch, native, s.supported.min, s.supported.max)
+		}
+		picked := min(native, s.supported.max)
+		if picked < native {
+			downgraded[s.name] = true
+		}
+		*s.version(&versions) = picked
+	}
+	return versions, downgraded, nil
+}
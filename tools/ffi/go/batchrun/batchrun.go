@@ -0,0 +1,166 @@
+// Package batchrun plans and reports batch runs over many bundles: it finds the
+// bundles a --bundle-dir or --glob run covers, processes them on a fixed number
+// of goroutines and writes one JSON Lines record per bundle followed by a
+// summary. The direct client and the worker-pool sample share it and differ
+// only in how one bundle is processed.
+package batchrun
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Line is one JSON Lines record of a batch run.
+type Line struct {
+	Bundle     string         `json:"bundle"`
+	DurationMS int64          `json:"duration_ms"`
+	Result     map[string]any `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Failure names a bundle that failed and why.
+type Failure struct {
+	Bundle string `json:"bundle"`
+	Error  string `json:"error"`
+}
+
+// Summary counts the outcome of a batch run. Callers with more to report
+// embed it in their own struct before passing it to WriteSummary.
+type Summary struct {
+	Bundles   int       `json:"bundles"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Failures  []Failure `json:"failures,omitempty"`
+}
+
+// Find lists the files a batch run processes, sorted. With dir, every regular
+// file under it is a candidate and pattern, when set, filters them by their
+// slash-separated path relative to dir (or by base name when pattern has no
+// slash); hidden files and directories are skipped. Without dir, pattern is
+// expanded with filepath.Glob.
+func Find(dir, pattern string) ([]string, error) {
+	var bundles []string
+	if dir == "" {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+				bundles = append(bundles, m)
+			}
+		}
+		return bundles, nil
+	}
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if pattern == "" || match(pattern, filepath.ToSlash(rel)) {
+			bundles = append(bundles, p)
+		}
+		return nil
+	})
+	slices.Sort(bundles)
+	return bundles, err
+}
+
+func match(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(pattern, rel)
+	return ok
+}
+
+// Key names a bundle's output subdirectory: its path relative to root, or for
+// glob matches outside any root, the path without its volume and leading
+// separators.
+func Key(root, bundle string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, bundle); err == nil && filepath.IsLocal(rel) {
+			return rel
+		}
+	}
+	key := strings.TrimPrefix(bundle, filepath.VolumeName(bundle))
+	key = strings.TrimLeft(key, `/\`)
+	if !filepath.IsLocal(key) {
+		return filepath.Base(bundle)
+	}
+	return key
+}
+
+// Run calls process for every bundle on up to jobs goroutines and writes one
+// Line per bundle to out as each finishes. It returns the summary, with
+// failures sorted by bundle, for the caller to finish with WriteSummary.
+func Run(bundles []string, jobs int, process func(bundle string) (map[string]any, error), out io.Writer) Summary {
+	todo := make(chan string)
+	lines := make(chan Line)
+	var wg sync.WaitGroup
+	for range max(jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for bundle := range todo {
+				started := time.Now()
+				result, err := process(bundle)
+				line := Line{Bundle: bundle, DurationMS: time.Since(started).Milliseconds(), Result: result}
+				if err != nil {
+					line.Error = err.Error()
+				}
+				lines <- line
+			}
+		}()
+	}
+	go func() {
+		for _, bundle := range bundles {
+			todo <- bundle
+		}
+		close(todo)
+		wg.Wait()
+		close(lines)
+	}()
+	enc := json.NewEncoder(out)
+	summary := Summary{Bundles: len(bundles)}
+	for line := range lines {
+		if line.Error != "" {
+			summary.Failures = append(summary.Failures, Failure{Bundle: line.Bundle, Error: line.Error})
+		}
+		_ = enc.Encode(line)
+	}
+	slices.SortFunc(summary.Failures, func(a, b Failure) int { return strings.Compare(a.Bundle, b.Bundle) })
+	summary.Failed = len(summary.Failures)
+	summary.Succeeded = summary.Bundles - summary.Failed
+	return summary
+}
+
+// WriteSummary writes the last line of a batch run, {"summary": summary}.
+func WriteSummary(out io.Writer, summary any) error {
+	return json.NewEncoder(out).Encode(map[string]any{"summary": summary})
+}
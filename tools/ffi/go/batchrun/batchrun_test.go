@@ -0,0 +1,101 @@
+package batchrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"a/one.bundle", "a/two.txt", "b/three.bundle", ".hidden/four.bundle", "a/.five.bundle"} {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	join := func(rels ...string) []string {
+		var out []string
+		for _, rel := range rels {
+			out = append(out, filepath.Join(dir, filepath.FromSlash(rel)))
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		dir, pattern string
+		want         []string
+	}{
+		{dir, "", join("a/one.bundle", "a/two.txt", "b/three.bundle")},
+		{dir, "*.bundle", join("a/one.bundle", "b/three.bundle")},
+		{dir, "b/*", join("b/three.bundle")},
+		{"", filepath.Join(dir, "b", "*"), join("b/three.bundle")},
+	} {
+		got, err := Find(tc.dir, tc.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("Find(%q, %q) = %v, want %v", tc.dir, tc.pattern, got, tc.want)
+		}
+	}
+	if _, err := Find(dir, "["); err == nil {
+		t.Error("Find accepted a malformed pattern")
+	}
+}
+
+func TestKey(t *testing.T) {
+	root := filepath.Join("data", "bundles")
+	for _, tc := range []struct{ root, bundle, want string }{
+		{root, filepath.Join(root, "a", "b.bundle"), filepath.Join("a", "b.bundle")},
+		{root, filepath.Join("elsewhere", "c.bundle"), filepath.Join("elsewhere", "c.bundle")},
+		{"", string(filepath.Separator) + filepath.Join("abs", "d.bundle"), filepath.Join("abs", "d.bundle")},
+		{"", filepath.Join("..", "e.bundle"), "e.bundle"},
+	} {
+		if got := Key(tc.root, tc.bundle); got != tc.want {
+			t.Errorf("Key(%q, %q) = %q, want %q", tc.root, tc.bundle, got, tc.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	bundles := []string{"c", "a", "b", "d"}
+	var out bytes.Buffer
+	summary := Run(bundles, 3, func(bundle string) (map[string]any, error) {
+		if bundle == "b" || bundle == "a" {
+			return nil, errors.New("broken " + bundle)
+		}
+		return map[string]any{"name": bundle}, nil
+	}, &out)
+	want := Summary{Bundles: 4, Succeeded: 2, Failed: 2, Failures: []Failure{{"a", "broken a"}, {"b", "broken b"}}}
+	if !slices.Equal(summary.Failures, want.Failures) || summary.Bundles != want.Bundles || summary.Succeeded != want.Succeeded || summary.Failed != want.Failed {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(bundles) {
+		t.Fatalf("%d lines, want %d: %s", len(lines), len(bundles), out.String())
+	}
+	for _, raw := range lines {
+		var line Line
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatal(err)
+		}
+		if (line.Error != "") != (line.Bundle == "a" || line.Bundle == "b") || (line.Error == "" && line.Result["name"] != line.Bundle) {
+			t.Errorf("line = %+v", line)
+		}
+	}
+	out.Reset()
+	if err := WriteSummary(&out, summary); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), `{"summary":{"bundles":4,"succeeded":2,"failed":2,`) {
+		t.Errorf("summary line = %s", out.String())
+	}
+}
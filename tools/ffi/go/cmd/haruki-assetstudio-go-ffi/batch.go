@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+
+	"haruki-assetstudio-go-ffi/assetstudio"
+	"haruki-assetstudio-go-ffi/batchrun"
+)
+
+// batchJobs caps jobs at what the library allows to be open at once, so
+// bundles are not failed by the context limit.
+func batchJobs(lib *assetstudio.Library, jobs int) int {
+	limits, err := lib.Limits()
+	switch {
+	case err != nil:
+		return 1
+	case !limits.SupportsMultipleContexts:
+		jobs = 1
+	case limits.MaxActiveContexts > 0:
+		jobs = min(jobs, limits.MaxActiveContexts)
+	}
+	return max(jobs, 1)
+}
+
+// runBatch processes bundles with batchrun.Run and writes its JSON Lines
+// report to out. With --image-out, each bundle's images go to a subdirectory
+// named after its path relative to root. It returns the number of failed
+// bundles.
+func runBatch(lib *assetstudio.Library, root string, bundles []string, jobs int, opts bundleOptions, out io.Writer) int {
+	summary := batchrun.Run(bundles, batchJobs(lib, jobs), func(bundle string) (map[string]any, error) {
+		bundleOpts := opts
+		if opts.imageOut != "" {
+			bundleOpts.imageOut = filepath.Join(opts.imageOut, batchrun.Key(root, bundle))
+		}
+		return processBundle(lib, bundle, bundleOpts)
+	}, out)
+	_ = batchrun.WriteSummary(out, summary)
+	return summary.Failed
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"haruki-assetstudio-go-ffi/assetstudio"
+)
+
+// runCapabilities implements `capabilities --ffi-library PATH`: print the
+// library's capabilities and limits, the negotiated sub-ABI versions, and any
+// version, layout or dependency mismatch that would make a full load fail.
+// Mismatches are reported, not returned, so a broken build can be inspected.
+func runCapabilities(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("capabilities", flag.ExitOnError)
+	libPath := fs.String("ffi-library", "", "Path to HarukiAssetStudioFFI dynamic library")
+	nativeDeps := nativeDepsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *libPath == "" {
+		return fmt.Errorf("--ffi-library is required")
+	}
+	in, err := assetstudio.Inspect(*libPath, loadOptions(*nativeDeps))
+	if err != nil {
+		return err
+	}
+	report := map[string]any{"backend": assetstudio.Backend, "abi_versions": in.ABIVersions, "capabilities": in.Capabilities, "limits": in.Limits}
+	if in.LayoutError != nil {
+		report["layout_error"] = in.LayoutError.Error()
+	}
+	if in.DependencyError != nil {
+		report["dependency_error"] = in.DependencyError.Error()
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
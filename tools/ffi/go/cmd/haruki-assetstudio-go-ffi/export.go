@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"haruki-assetstudio-go-ffi/assetstudio"
+	"haruki-assetstudio-go-ffi/payloadbundle"
+	"haruki-assetstudio-go-ffi/rgbair"
+)
+
+// exportChunk bounds how many payloads are held in memory at once.
+const exportChunk = 256
+
+type exportFailure struct {
+	PathID     int64  `json:"path_id"`
+	SourceFile string `json:"source_file,omitempty"`
+	Error      string `json:"error"`
+}
+
+type exportSummary struct {
+	OutputDir  string          `json:"output_dir"`
+	AssetCount int             `json:"asset_count"`
+	Written    int             `json:"written"`
+	Failed     []exportFailure `json:"failed,omitempty"`
+}
+
+// runExport implements `export --ffi-library PATH --bundle PATH --out DIR`:
+// read every selected object with its default read kind and write it under
+// DIR at the path assetstudio.OutputPath gives it. RGBA IR images are encoded
+// with --image-encoding first, and payload bundles are unpacked into a
+// directory per object. Per-object failures are reported in the JSON summary
+// instead of stopping the export.
+func runExport(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	libPath := fs.String("ffi-library", "", "Path to HarukiAssetStudioFFI dynamic library")
+	nativeDeps := nativeDepsFlag(fs)
+	bundle := fs.String("bundle", "", "UnityFS bundle path")
+	unity := fs.String("unity-version", "2022.3.21f1", "Unity version fallback")
+	outDir := fs.String("out", "", "Directory to write exported assets under")
+	typesFlag := fs.String("types", "", "Comma-separated asset types to export, e.g. Texture2D,TextAsset")
+	imageEncoding := fs.String("image-encoding", "png", "Encoding for raw_rgba images: png, jpg or webp")
+	timeout := fs.Duration("timeout", 0, "Give up on the bundle after this long, e.g. 2m (0 means no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *libPath == "" || *bundle == "" || *outDir == "" {
+		return fmt.Errorf("--ffi-library, --bundle and --out are required")
+	}
+	format, err := rgbair.ParseFormat(*imageEncoding)
+	if err != nil {
+		return err
+	}
+	lib, err := loadLibrary(*libPath, *nativeDeps)
+	if err != nil {
+		return err
+	}
+	var types []string
+	if *typesFlag != "" {
+		types = strings.Split(*typesFlag, ",")
+	}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	c, err := lib.OpenContext(ctx, *bundle, *unity, types)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	assets, err := c.ListContext(ctx, types)
+	if err != nil {
+		return err
+	}
+	// Path ids are only unique within one source file, so reads are paired
+	// with their assets by position: DefaultReadItems makes one item per typed
+	// asset and results come back in item order.
+	selected := make([]assetstudio.AssetInfo, 0, len(assets))
+	for _, a := range assets {
+		if strings.TrimSpace(a.Type) != "" {
+			selected = append(selected, a)
+		}
+	}
+	summary := exportSummary{OutputDir: *outDir, AssetCount: len(assets)}
+	paths := &assetstudio.OutputPaths{Root: *outDir}
+	items := assetstudio.DefaultReadItems(selected, assetstudio.DefaultImageFormat)
+	for start := 0; start < len(items); start += exportChunk {
+		end := min(start+exportChunk, len(items))
+		reads, err := c.ReadContext(ctx, items[start:end])
+		if err != nil && !errors.Is(err, assetstudio.ErrPartialFailure) {
+			return err
+		}
+		if len(reads) != end-start {
+			return fmt.Errorf("read returned %d results for %d items", len(reads), end-start)
+		}
+		for i, r := range reads {
+			a := selected[start+i]
+			err := fmt.Errorf("read returned path_id %d", r.PathID)
+			if r.PathID == a.PathID {
+				err = exportRead(paths, a, r, format)
+			}
+			if err != nil {
+				summary.Failed = append(summary.Failed, exportFailure{PathID: a.PathID, SourceFile: a.SourceFile, Error: err.Error()})
+				continue
+			}
+			summary.Written++
+		}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
+
+// exportRead writes one read result under paths.Root.
+func exportRead(paths *assetstudio.OutputPaths, a assetstudio.AssetInfo, r assetstudio.ReadResult, format rgbair.Format) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	payload, ext := r.Payload, assetstudio.OutputExtension(a, r)
+	if assetstudio.IsBundleKind(r.PayloadKind) {
+		target, err := paths.Claim(assetstudio.OutputPath(a, ext))
+		if err != nil {
+			return err
+		}
+		return writeBundle(target, strings.ToLower(strings.TrimSpace(r.PayloadKind)), payload, format)
+	}
+	if rgbair.IsRGBAIR(payload) {
+		encoded, err := rgbair.DecodeEncode(payload, format)
+		if err != nil {
+			return err
+		}
+		payload, ext = encoded, format.Extension()
+	}
+	name, err := paths.Claim(assetstudio.OutputPath(a, ext))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, payload, 0o644)
+}
+
+// writeBundle unpacks a payload bundle into the directory target. The RGBA IR
+// slices of image_array_bundle_raw_rgba are encoded to format first; other
+// bundles are written as they are.
+func writeBundle(target, kind string, payload []byte, format rgbair.Format) error {
+	if kind != "image_array_bundle_raw_rgba" {
+		_, err := payloadbundle.Write(target, payload)
+		return err
+	}
+	entries, err := payloadbundle.Parse(payload)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		encoded, err := rgbair.DecodeEncode(e.Data, format)
+		if err != nil {
+			return fmt.Errorf("bundle entry %s: %w", e.Name, err)
+		}
+		name := payloadbundle.EntryTarget(target, e.Name)
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + "." + format.Extension()
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, encoded, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"haruki-assetstudio-go-ffi/assetstudio"
+)
+
+// nativeDepsFlag registers --native-deps on fs.
+func nativeDepsFlag(fs *flag.FlagSet) *string {
+	return fs.String("native-deps", "", `Comma-separated native dependencies to preload, as names next to the library or paths; "none" preloads nothing (default: the decoder libraries, unless the library resolves its own)`)
+}
+
+// loadOptions turns the --native-deps value into LoadOptions.
+func loadOptions(deps string) assetstudio.LoadOptions {
+	var opts assetstudio.LoadOptions
+	switch deps {
+	case "":
+	case "none":
+		opts.Dependencies = []string{}
+	default:
+		opts.Dependencies = strings.Split(deps, ",")
+	}
+	return opts
+}
+
+// loadLibrary loads the library at path with the dependencies given to
+// --native-deps.
+func loadLibrary(path, deps string) (*assetstudio.Library, error) {
+	return assetstudio.LoadWithOptions(path, loadOptions(deps))
+}
@@ -0,0 +1,183 @@
+// Command haruki-assetstudio-go-ffi opens one bundle through the assetstudio
+// package, lists its objects and optionally reads them, printing a JSON
+// summary. --bundle-dir and --glob do the same for many bundles at once and
+// print one JSON line per bundle. `haruki-assetstudio-go-ffi capabilities`
+// prints what the loaded library supports and `haruki-assetstudio-go-ffi
+// export` writes a bundle's objects to disk.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"haruki-assetstudio-go-ffi/assetstudio"
+	"haruki-assetstudio-go-ffi/batchrun"
+	"haruki-assetstudio-go-ffi/rgbair"
+)
+
+// writeImages decodes every RGBA IR payload in reads and writes it to dir as
+// <path_id>.<ext>. Reads with other payloads are skipped.
+func writeImages(dir, encoding string, reads []assetstudio.ReadResult) (int, error) {
+	format, err := rgbair.ParseFormat(encoding)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, r := range reads {
+		if r.Err() != nil || !rgbair.IsRGBAIR(r.Payload) {
+			continue
+		}
+		encoded, err := rgbair.DecodeEncode(r.Payload, format)
+		if err != nil {
+			return written, fmt.Errorf("path_id=%d: %w", r.PathID, err)
+		}
+		name := filepath.Join(dir, fmt.Sprintf("%d.%s", r.PathID, format.Extension()))
+		if err := os.WriteFile(name, encoded, 0o644); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "capabilities" {
+		if err := runCapabilities(os.Args[2:], os.Stdout); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:], os.Stdout); err != nil {
+			panic(err)
+		}
+		return
+	}
+	libPath := flag.String("ffi-library", "", "Path to HarukiAssetStudioFFI dynamic library")
+	nativeDeps := nativeDepsFlag(flag.CommandLine)
+	bundle := flag.String("bundle", "", "UnityFS bundle path")
+	bundleDir := flag.String("bundle-dir", "", "Process every file under this directory, e.g. an asset_save_dir")
+	glob := flag.String("glob", "", "Process the bundles matching this pattern; with --bundle-dir, filter the walked files by it")
+	jobs := flag.Int("jobs", 4, "Bundles processed at once with --bundle-dir or --glob")
+	unity := flag.String("unity-version", "2022.3.21f1", "Unity version fallback")
+	readImages := flag.Bool("read-images", false, "Read Texture2D raw_rgba payloads")
+	readObjects := flag.Bool("read-objects", false, "Read every object with its default read kind")
+	imageFormat := flag.String("image-format", assetstudio.DefaultImageFormat, "Image format for image reads")
+	callerBuffers := flag.Bool("caller-buffers", false, "Read into pooled Go buffers instead of native allocations")
+	typesFlag := flag.String("types", "", "Comma-separated asset types to load and list, e.g. Texture2D,Sprite")
+	imageOut := flag.String("image-out", "", "Directory to write decoded raw_rgba image reads to")
+	imageEncoding := flag.String("image-encoding", "png", "Encoding for --image-out: png, jpg or webp")
+	timeout := flag.Duration("timeout", 0, "Give up on the bundle after this long, e.g. 2m (0 means no limit)")
+	flag.Parse()
+	batch := *bundleDir != "" || *glob != ""
+	if *libPath == "" || (*bundle == "") != batch {
+		panic("--ffi-library and one of --bundle or --bundle-dir/--glob are required")
+	}
+	lib, err := loadLibrary(*libPath, *nativeDeps)
+	if err != nil {
+		panic(err)
+	}
+	opts := bundleOptions{
+		unityVersion:  *unity,
+		readImages:    *readImages,
+		readObjects:   *readObjects,
+		imageFormat:   *imageFormat,
+		imageOut:      *imageOut,
+		imageEncoding: *imageEncoding,
+		timeout:       *timeout,
+	}
+	if *typesFlag != "" {
+		opts.types = strings.Split(*typesFlag, ",")
+	}
+	if *callerBuffers {
+		lib.SetBufferPool(assetstudio.NewBufferPool())
+	}
+	if batch {
+		bundles, err := batchrun.Find(*bundleDir, *glob)
+		if err != nil {
+			panic(err)
+		}
+		if failed := runBatch(lib, *bundleDir, bundles, *jobs, opts, os.Stdout); failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	result, err := processBundle(lib, *bundle, opts)
+	if err != nil {
+		panic(err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(result)
+}
+
+// bundleOptions are the per-bundle settings shared by single-bundle and batch
+// runs.
+type bundleOptions struct {
+	unityVersion  string
+	types         []string
+	readImages    bool
+	readObjects   bool
+	imageFormat   string
+	imageOut      string
+	imageEncoding string
+	timeout       time.Duration
+}
+
+// processBundle opens one bundle, lists it and runs the requested reads,
+// returning the JSON summary main prints for it. Partial read failures are
+// reported in the summary, not as an error.
+func processBundle(lib *assetstudio.Library, bundle string, opts bundleOptions) (map[string]any, error) {
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	c, err := lib.OpenContext(ctx, bundle, opts.unityVersion, opts.types)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	assets, err := c.ListContext(ctx, opts.types)
+	if err != nil {
+		return nil, err
+	}
+	typeCounts := map[string]int{}
+	for _, a := range assets {
+		typeCounts[a.Type]++
+	}
+	result := map[string]any{"asset_count": len(assets), "types": typeCounts}
+	if opts.readImages {
+		reads, err := c.ReadImagesContext(ctx, assets)
+		if err != nil && !errors.Is(err, assetstudio.ErrPartialFailure) {
+			return nil, err
+		}
+		result["reads"] = reads
+		if opts.imageOut != "" {
+			written, err := writeImages(opts.imageOut, opts.imageEncoding, reads)
+			if err != nil {
+				return nil, err
+			}
+			result["images_written"] = written
+		}
+	}
+	if opts.readObjects {
+		reads, err := c.ReadContext(ctx, assetstudio.DefaultReadItems(assets, opts.imageFormat))
+		if err != nil && !errors.Is(err, assetstudio.ErrPartialFailure) {
+			return nil, err
+		}
+		result["object_reads"] = reads
+	}
+	return result, nil
+}
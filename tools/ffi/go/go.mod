@@ -1,3 +1,8 @@
 module haruki-assetstudio-go-ffi
 
 go 1.25.1
+
+require (
+	github.com/HugoSmits86/nativewebp v0.9.3
+	github.com/ebitengine/purego v0.11.1
+)
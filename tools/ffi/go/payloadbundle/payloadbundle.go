@@ -0,0 +1,281 @@
+// Package payloadbundle splits multi-file object payloads (for example a mesh
+// plus its materials) into named entries.
+//
+// Two layouts exist, mirroring parse_payload_bundle_borrowed in
+// src/core/export_pipeline/implementation/payload.rs:
+//
+//   - v2 (HAPB): u32 magic 0x42504148, u16 version 2, u16 header_len (>= 20),
+//     u32 entry count, u64 total entry data bytes; then at header_len each entry
+//     as u32 name_len, u64 data_len, name, data.
+//   - v1 (legacy): "HARUKI_ASSET_PAYLOAD_BUNDLE_V1", u32 entry count, every
+//     entry header (u32 name_len, u64 data_len, name), then every entry's data.
+//
+// All integers are little-endian. Entry data is a subslice of the input, not a
+// copy.
+package payloadbundle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	V1Magic = "HARUKI_ASSET_PAYLOAD_BUNDLE_V1"
+
+	V2Magic     uint32 = 0x42504148
+	V2Version   uint16 = 2
+	V2HeaderLen        = 20
+)
+
+var ErrInvalidMagic = errors.New("native payload bundle has invalid magic")
+
+// Entry is one named file inside a payload bundle. Data aliases the parsed
+// payload.
+type Entry struct {
+	Name string
+	Data []byte
+}
+
+// IsBundle reports whether payload starts with either bundle magic.
+func IsBundle(payload []byte) bool {
+	return isV2(payload) || bytes.HasPrefix(payload, []byte(V1Magic))
+}
+
+func isV2(payload []byte) bool {
+	return len(payload) >= 4 && binary.LittleEndian.Uint32(payload) == V2Magic
+}
+
+// Parse splits a v1 or v2 payload bundle into its entries.
+func Parse(payload []byte) ([]Entry, error) {
+	if isV2(payload) {
+		r := reader{buf: payload, pos: 4}
+		version, err := r.u16()
+		if err != nil {
+			return nil, err
+		}
+		if version != V2Version {
+			return nil, fmt.Errorf("native payload bundle has unsupported version %d", version)
+		}
+		headerLen, err := r.u16()
+		if err != nil {
+			return nil, err
+		}
+		if int(headerLen) < V2HeaderLen || int(headerLen) > len(payload) {
+			return nil, fmt.Errorf("native payload bundle has invalid header length %d", headerLen)
+		}
+		count, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		dataBytes, err := r.u64()
+		if err != nil {
+			return nil, err
+		}
+		r.pos = int(headerLen)
+		return parseInterleaved(r, int(count), dataBytes)
+	}
+	if bytes.HasPrefix(payload, []byte(V1Magic)) {
+		r := reader{buf: payload, pos: len(V1Magic)}
+		count, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		return parseGrouped(r, int(count))
+	}
+	return nil, ErrInvalidMagic
+}
+
+func parseInterleaved(r reader, count int, expectedDataBytes uint64) ([]Entry, error) {
+	entries := make([]Entry, 0, min(count, r.remaining()/12))
+	var observed uint64
+	for range count {
+		nameLen, dataLen, err := r.entryHeader()
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.name(nameLen)
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.data(dataLen)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Name: name, Data: data})
+		observed += dataLen
+	}
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+	if observed != expectedDataBytes {
+		return nil, fmt.Errorf("native payload bundle data byte mismatch: header says %d, entries hold %d", expectedDataBytes, observed)
+	}
+	return entries, nil
+}
+
+func parseGrouped(r reader, count int) ([]Entry, error) {
+	type header struct {
+		name    string
+		dataLen uint64
+	}
+	headers := make([]header, 0, min(count, r.remaining()/12))
+	for range count {
+		nameLen, dataLen, err := r.entryHeader()
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.name(nameLen)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header{name, dataLen})
+	}
+	entries := make([]Entry, 0, len(headers))
+	for _, h := range headers {
+		data, err := r.data(h.dataLen)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Name: h.name, Data: data})
+	}
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf) - r.pos
+}
+
+func (r *reader) take(n int) ([]byte, error) {
+	if r.remaining() < n {
+		return nil, errors.New("native payload bundle is truncated")
+	}
+	b := r.buf[r.pos : r.pos+n : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) u16() (uint16, error) {
+	b, err := r.take(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *reader) u32() (uint32, error) {
+	b, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *reader) u64() (uint64, error) {
+	b, err := r.take(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (r *reader) entryHeader() (uint32, uint64, error) {
+	nameLen, err := r.u32()
+	if err != nil {
+		return 0, 0, err
+	}
+	dataLen, err := r.u64()
+	return nameLen, dataLen, err
+}
+
+func (r *reader) name(n uint32) (string, error) {
+	if uint64(r.remaining()) < uint64(n) {
+		return "", errors.New("native payload bundle has truncated entry name")
+	}
+	b, _ := r.take(int(n))
+	if !utf8.Valid(b) {
+		return "", errors.New("native payload bundle entry name is not utf-8")
+	}
+	return string(b), nil
+}
+
+func (r *reader) data(n uint64) ([]byte, error) {
+	if uint64(r.remaining()) < n {
+		return nil, errors.New("native payload bundle has truncated entry data")
+	}
+	return r.take(int(n))
+}
+
+func (r *reader) finish() error {
+	if r.remaining() != 0 {
+		return fmt.Errorf("native payload bundle has %d trailing byte(s)", r.remaining())
+	}
+	return nil
+}
+
+// SafePath turns an entry name into a relative path that stays below the
+// directory it is joined to: root, "." and ".." components are dropped, and an
+// empty result becomes "payload.bin".
+func SafePath(name string) string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(name, isSeparator) {
+		if part == "." || part == ".." {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "payload.bin"
+	}
+	return filepath.Join(parts...)
+}
+
+func isSeparator(r rune) bool {
+	return r == '/' || r == os.PathSeparator
+}
+
+// EntryTarget is where an entry of the bundle read for target lands:
+// <target>/<SafePath(name)>. Unlike payload_bundle_entry_target on the Rust
+// side, target keeps its extension, so foo.png and foo.json unpack into
+// different directories and a target claimed once per run is also a directory
+// used once per run.
+func EntryTarget(target, name string) string {
+	if base := filepath.Base(target); base == "." || base == string(filepath.Separator) {
+		target = filepath.Join(target, "asset")
+	}
+	return filepath.Join(target, SafePath(name))
+}
+
+// Write parses payload and writes every entry below the directory target,
+// returning the written paths.
+func Write(target string, payload []byte) ([]string, error) {
+	entries, err := Parse(payload)
+	if err != nil {
+		return nil, err
+	}
+	written := make([]string, 0, len(entries))
+	for _, e := range entries {
+		path := EntryTarget(target, e.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, e.Data, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
@@ -0,0 +1,108 @@
+package payloadbundle
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	name string
+	data string
+}
+
+func makeV2(entries []testEntry, dataBytes uint64) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, V2Magic)
+	b = binary.LittleEndian.AppendUint16(b, V2Version)
+	b = binary.LittleEndian.AppendUint16(b, V2HeaderLen)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(entries)))
+	b = binary.LittleEndian.AppendUint64(b, dataBytes)
+	for _, e := range entries {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(e.name)))
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(e.data)))
+		b = append(b, e.name...)
+		b = append(b, e.data...)
+	}
+	return b
+}
+
+func makeV1(entries []testEntry) []byte {
+	b := []byte(V1Magic)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(entries)))
+	for _, e := range entries {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(e.name)))
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(e.data)))
+		b = append(b, e.name...)
+	}
+	for _, e := range entries {
+		b = append(b, e.data...)
+	}
+	return b
+}
+
+var sample = []testEntry{{"mesh.obj", "v 0 0 0\n"}, {"materials/body.mtl", "newmtl body\n"}}
+
+func TestParseBothVersions(t *testing.T) {
+	for name, payload := range map[string][]byte{
+		"v2": makeV2(sample, uint64(len(sample[0].data)+len(sample[1].data))),
+		"v1": makeV1(sample),
+	} {
+		entries, err := Parse(payload)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(entries) != len(sample) {
+			t.Fatalf("%s: got %d entries", name, len(entries))
+		}
+		for i, e := range entries {
+			if e.Name != sample[i].name || string(e.Data) != sample[i].data {
+				t.Errorf("%s: entry %d = %q %q", name, i, e.Name, e.Data)
+			}
+		}
+		// Entries must alias the payload rather than copy it.
+		entries[0].Data[0] = 'X'
+		if !strings.Contains(string(payload), "X 0 0 0") {
+			t.Errorf("%s: entry data is not a subslice of the payload", name)
+		}
+	}
+}
+
+func TestParseRejectsMalformedBundles(t *testing.T) {
+	good := makeV2(sample, 20)
+	cases := map[string][]byte{
+		"magic":         []byte("not a bundle"),
+		"trailing":      append(makeV2(sample, 20), 0),
+		"data mismatch": makeV2(sample, 21),
+		"truncated":     good[:len(good)-1],
+		"version":       append(binary.LittleEndian.AppendUint32(nil, V2Magic), 3, 0, 20, 0),
+		"v1 trailing":   append(makeV1(sample), 0),
+	}
+	for name, payload := range cases {
+		if _, err := Parse(payload); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSafePath(t *testing.T) {
+	cases := map[string]string{
+		"mesh.obj":         "mesh.obj",
+		"/abs/mesh.obj":    filepath.Join("abs", "mesh.obj"),
+		"../../etc/passwd": filepath.Join("etc", "passwd"),
+		"./a/./b":          filepath.Join("a", "b"),
+		"":                 "payload.bin",
+		"..":               "payload.bin",
+	}
+	for in, want := range cases {
+		if got := SafePath(in); got != want {
+			t.Errorf("SafePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got, want := EntryTarget(filepath.Join("out", "model.obj"), "../mat.mtl"), filepath.Join("out", "model.obj", "mat.mtl"); got != want {
+		t.Errorf("EntryTarget = %q, want %q", got, want)
+	}
+	if png, json := EntryTarget(filepath.Join("out", "foo.png"), "0.png"), EntryTarget(filepath.Join("out", "foo.json"), "0.png"); png == json {
+		t.Errorf("foo.png and foo.json share entry target %q", png)
+	}
+}
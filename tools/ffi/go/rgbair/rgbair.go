@@ -0,0 +1,175 @@
+// Package rgbair decodes the native RGBA IR container that direct reads return
+// for image_format=raw_rgba, and encodes the decoded image to PNG, JPEG or WebP.
+//
+// The layout matches parse_native_rgba_ir_payload in
+// src/core/export_pipeline/implementation/payload.rs: a 16-byte magic, then
+// little-endian u32 width, height, stride and pixel format, padded to a 36-byte
+// header. Rows are stride bytes apart; only the first width*4 bytes of a row
+// are pixels.
+package rgbair
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+const (
+	Magic     = "HARUKI_RGBAIR_V1"
+	HeaderLen = 36
+
+	// PixelFormatRGBA8 is straight (non-premultiplied) 8-bit RGBA.
+	PixelFormatRGBA8 = 1
+)
+
+var ErrInvalidMagic = errors.New("native raw RGBA image payload has invalid magic")
+
+// Header is the fixed part of an RGBA IR payload.
+type Header struct {
+	Width       uint32
+	Height      uint32
+	Stride      uint32
+	PixelFormat uint32
+}
+
+// IsRGBAIR reports whether payload starts with the RGBA IR magic.
+func IsRGBAIR(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte(Magic))
+}
+
+// ParseHeader validates the header and that payload holds every row.
+func ParseHeader(payload []byte) (Header, error) {
+	if len(payload) < HeaderLen {
+		return Header{}, fmt.Errorf("native raw RGBA image payload is too short: %d bytes", len(payload))
+	}
+	if !IsRGBAIR(payload) {
+		return Header{}, ErrInvalidMagic
+	}
+	h := Header{
+		Width:       binary.LittleEndian.Uint32(payload[16:]),
+		Height:      binary.LittleEndian.Uint32(payload[20:]),
+		Stride:      binary.LittleEndian.Uint32(payload[24:]),
+		PixelFormat: binary.LittleEndian.Uint32(payload[28:]),
+	}
+	if h.PixelFormat != PixelFormatRGBA8 {
+		return Header{}, fmt.Errorf("native raw RGBA image payload has unsupported pixel format %d", h.PixelFormat)
+	}
+	rowBytes := uint64(h.Width) * 4
+	if uint64(h.Stride) < rowBytes {
+		return Header{}, fmt.Errorf("native raw RGBA image payload has invalid stride %d for width %d", h.Stride, h.Width)
+	}
+	need := uint64(h.Stride)*uint64(h.Height) + HeaderLen
+	if uint64(len(payload)) < need {
+		return Header{}, fmt.Errorf("native raw RGBA image payload is truncated: expected at least %d, got %d", need, len(payload))
+	}
+	return h, nil
+}
+
+// Decode copies the pixels of an RGBA IR payload into a new image, dropping
+// any stride padding.
+func Decode(payload []byte) (*image.NRGBA, error) {
+	h, err := ParseHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, int(h.Width), int(h.Height)))
+	rowBytes := int(h.Width) * 4
+	pixels := payload[HeaderLen:]
+	if int(h.Stride) == rowBytes {
+		copy(img.Pix, pixels[:rowBytes*int(h.Height)])
+		return img, nil
+	}
+	for y := 0; y < int(h.Height); y++ {
+		src := pixels[y*int(h.Stride):]
+		copy(img.Pix[y*img.Stride:y*img.Stride+rowBytes], src[:rowBytes])
+	}
+	return img, nil
+}
+
+// Format is an encoded output image format.
+type Format int
+
+const (
+	PNG Format = iota
+	JPEG
+	WebP
+)
+
+// ParseFormat accepts the same names as export.images.formats: png, jpg/jpeg
+// and webp.
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "png":
+		return PNG, nil
+	case "jpg", "jpeg":
+		return JPEG, nil
+	case "webp":
+		return WebP, nil
+	default:
+		return 0, fmt.Errorf("unsupported image format %q", value)
+	}
+}
+
+// Extension returns the file extension for f, without the dot.
+func (f Format) Extension() string {
+	switch f {
+	case JPEG:
+		return "jpg"
+	case WebP:
+		return "webp"
+	default:
+		return "png"
+	}
+}
+
+func (f Format) String() string {
+	return f.Extension()
+}
+
+// JPEGQuality is used for JPEG output.
+const JPEGQuality = 90
+
+// Encode writes img in format f. JPEG has no alpha channel, so the image is
+// flattened onto opaque black first; PNG and WebP (lossless) keep alpha.
+func Encode(w io.Writer, img image.Image, f Format) error {
+	switch f {
+	case PNG:
+		return png.Encode(w, img)
+	case JPEG:
+		return jpeg.Encode(w, flatten(img), &jpeg.Options{Quality: JPEGQuality})
+	case WebP:
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported image format %d", int(f))
+	}
+}
+
+// DecodeEncode decodes an RGBA IR payload and returns it encoded as f.
+func DecodeEncode(payload []byte, f Format) ([]byte, error) {
+	img, err := Decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func flatten(img image.Image) image.Image {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
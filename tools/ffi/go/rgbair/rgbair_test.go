@@ -0,0 +1,84 @@
+package rgbair
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func makePayload(width, height, stride, pixelFormat uint32, pixels []byte) []byte {
+	payload := []byte(Magic)
+	for _, v := range []uint32{width, height, stride, pixelFormat, 0} {
+		payload = binary.LittleEndian.AppendUint32(payload, v)
+	}
+	return append(payload, pixels...)
+}
+
+func TestDecodeDropsStridePadding(t *testing.T) {
+	pixels := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 0xee, 0xee,
+		9, 10, 11, 12, 13, 14, 15, 16, 0xee, 0xee,
+	}
+	img, err := Decode(makePayload(2, 2, 10, PixelFormatRGBA8, pixels))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	if !bytes.Equal(img.Pix, want) {
+		t.Fatalf("pixels = %v, want %v", img.Pix, want)
+	}
+}
+
+func TestParseHeaderRejectsBadPayloads(t *testing.T) {
+	cases := map[string][]byte{
+		"short":        []byte(Magic),
+		"magic":        append([]byte("HARUKI_RGBAIR_V2"), make([]byte, 20)...),
+		"pixel format": makePayload(1, 1, 4, 2, make([]byte, 4)),
+		"stride":       makePayload(2, 1, 4, PixelFormatRGBA8, make([]byte, 8)),
+		"truncated":    makePayload(1, 2, 4, PixelFormatRGBA8, make([]byte, 4)),
+	}
+	for name, payload := range cases {
+		if _, err := ParseHeader(payload); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEncodeFormats(t *testing.T) {
+	payload := makePayload(2, 1, 8, PixelFormatRGBA8, []byte{255, 0, 0, 255, 0, 255, 0, 128})
+	for _, f := range []Format{PNG, JPEG, WebP} {
+		encoded, err := DecodeEncode(payload, f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		var decodeErr error
+		switch f {
+		case PNG:
+			_, decodeErr = png.Decode(bytes.NewReader(encoded))
+		case JPEG:
+			_, decodeErr = jpeg.Decode(bytes.NewReader(encoded))
+		case WebP:
+			if len(encoded) < 16 || string(encoded[:4]) != "RIFF" || string(encoded[8:16]) != "WEBPVP8L" {
+				decodeErr = errors.New("missing RIFF WEBP VP8L header")
+			}
+		}
+		if decodeErr != nil {
+			t.Fatalf("%s: round trip: %v", f, decodeErr)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, want := range map[string]Format{"png": PNG, "JPG": JPEG, "jpeg": JPEG, " webp ": WebP} {
+		got, err := ParseFormat(value)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v", value, got, err)
+		}
+	}
+	if _, err := ParseFormat("bmp"); err == nil {
+		t.Error("expected error for bmp")
+	}
+}